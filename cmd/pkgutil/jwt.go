@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/jwt"
+)
+
+func runJWT(args []string, opts map[string]string) error {
+	if len(args) == 0 {
+		return usageError("missing jwt subcommand")
+	}
+	switch args[0] {
+	case "decode":
+		token, err := readToken(args[1:])
+		if err != nil {
+			return err
+		}
+		return printToken(token)
+	case "verify":
+		token, err := readToken(args[1:])
+		if err != nil {
+			return err
+		}
+		return verifyToken(token, opts)
+	case "sign":
+		return signToken(opts)
+	}
+	return usageError("unknown jwt subcommand %q", args[0])
+}
+
+// readToken 从参数或标准输入读取令牌
+func readToken(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", usageError("missing token")
+	}
+	if args[0] != "-" {
+		return strings.TrimSpace(args[0]), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// printToken 打印令牌头、claims和可读的时间
+func printToken(token string) error {
+	header, claims, err := jwt.DecodeUnverified(token)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Header:")
+	printJSON(header)
+	fmt.Println("Claims:")
+	printJSON(claims)
+
+	now := time.Now()
+	var lines []string
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		n, ok := claims[name].(json.Number)
+		if !ok {
+			continue
+		}
+		sec, err := n.Int64()
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("  %s  %s (invalid)", name, n))
+			continue
+		}
+		t := time.Unix(sec, 0)
+		lines = append(lines, fmt.Sprintf("  %s  %s (%s)", name, t.Format("2006-01-02 15:04:05 -0700"), relative(name, t, now)))
+	}
+	if len(lines) > 0 {
+		fmt.Println("Times:")
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+func relative(name string, t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	switch {
+	case name == "exp" && d <= 0:
+		return fmt.Sprintf("expired %s ago", -d)
+	case name == "nbf" && d > 0:
+		return fmt.Sprintf("not valid for another %s", d)
+	case d > 0:
+		return fmt.Sprintf("in %s", d)
+	}
+	return fmt.Sprintf("%s ago", -d)
+}
+
+func printJSON(v interface{}) {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(b))
+}
+
+// verifyToken 验签并校验claims，失败时返回具体的校验错误
+func verifyToken(token string, opts map[string]string) error {
+	header, _, err := jwt.DecodeUnverified(token)
+	if err != nil {
+		return err
+	}
+	alg := opts["alg"]
+	if alg == "" {
+		alg, _ = header["alg"].(string)
+	}
+	kid, _ := header["kid"].(string)
+	key, err := loadKey(opts, kid, alg)
+	if err != nil {
+		return err
+	}
+	// 只用公钥验签
+	key = &jwt.Key{ID: key.ID, Algorithm: key.Algorithm, Public: key.Public}
+
+	cfg := jwt.ManagerConfig{VerifyKeys: []*jwt.Key{key}, Issuer: opts["iss"], Audience: splitList(opts["aud"])}
+	if v := opts["leeway"]; v != "" {
+		if cfg.Leeway, err = time.ParseDuration(v); err != nil {
+			return usageError("invalid --leeway: %s", err)
+		}
+	}
+	m, err := jwt.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	if err := printToken(token); err != nil {
+		return err
+	}
+	if err := m.Parse(token, &jwt.RegisteredClaims{}); err != nil {
+		fmt.Println("Result: invalid")
+		return err
+	}
+	fmt.Println("Result: valid")
+	return nil
+}
+
+// signToken 按 --claims 签发令牌并输出
+func signToken(opts map[string]string) error {
+	raw := opts["claims"]
+	if raw == "" {
+		raw = "{}"
+	}
+	data, err := readValue(raw)
+	if err != nil {
+		return err
+	}
+	claims := &mapClaims{}
+	if err := json.Unmarshal(data, claims); err != nil {
+		return usageError("invalid --claims: %s", err)
+	}
+	key, err := loadKey(opts, opts["kid"], opts["alg"])
+	if err != nil {
+		return err
+	}
+	cfg := jwt.ManagerConfig{SigningKey: key, Issuer: opts["iss"], Audience: splitList(opts["aud"])}
+	if v := opts["ttl"]; v != "" {
+		if cfg.TTL, err = time.ParseDuration(v); err != nil {
+			return usageError("invalid --ttl: %s", err)
+		}
+	}
+	m, err := jwt.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	token, err := m.Sign(claims)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// loadKey --key 以@开头时从文件读取PEM密钥，否则作为HMAC密钥
+func loadKey(opts map[string]string, kid, alg string) (*jwt.Key, error) {
+	v, ok := opts["key"]
+	if !ok || v == "" {
+		return nil, usageError("missing --key")
+	}
+	if !strings.HasPrefix(v, "@") {
+		if alg == "" {
+			alg = jwt.AlgHS256
+		}
+		return jwt.NewHMACKey(kid, alg, []byte(v))
+	}
+	data, err := readValue(v)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", v[1:])
+	}
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		return jwt.NewPrivateKeyFromPEM(kid, alg, data)
+	}
+	return jwt.NewPublicKeyFromPEM(kid, alg, data)
+}
+
+// readValue 以@开头时读取文件内容
+func readValue(v string) ([]byte, error) {
+	if strings.HasPrefix(v, "@") {
+		return os.ReadFile(v[1:])
+	}
+	return []byte(v), nil
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// mapClaims 任意claims，注册的claims由 Manager 填充
+type mapClaims struct {
+	jwt.RegisteredClaims
+	extra map[string]interface{}
+}
+
+func (c *mapClaims) MarshalJSON() ([]byte, error) {
+	reg, err := json.Marshal(c.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]interface{}, len(c.extra))
+	for k, v := range c.extra {
+		merged[k] = v
+	}
+	dec := json.NewDecoder(bytes.NewReader(reg))
+	dec.UseNumber()
+	if err := dec.Decode(&merged); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+func (c *mapClaims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.RegisteredClaims); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(&c.extra)
+}
@@ -0,0 +1,71 @@
+// pkgutil 命令行工具，用于本地排查令牌等问题，不会把任何数据发送到外部
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/atime/command"
+)
+
+const usage = `Usage: pkgutil <command> [options]
+
+Commands:
+  jwt decode <token>                    解码令牌头和claims，不验签
+  jwt verify --key=<key> <token>        验签并校验时间、签发者、受众
+  jwt sign --claims=<json> --key=<key>  签发令牌
+
+Options:
+  --key      HMAC密钥，或 @文件 读取PEM格式的RSA/ECDSA/Ed25519密钥
+  --alg      签名算法，verify 默认取令牌头，sign 默认 HS256 或按密钥类型推断
+  --kid      sign 时写入令牌头的kid
+  --claims   JSON格式的claims，或 @文件
+  --ttl      sign 时的有效期，如 2h，默认2小时
+  --iss      verify 时校验签发者，sign 时写入签发者
+  --aud      verify 时校验受众，sign 时写入受众，多个用逗号分隔
+  --leeway   verify 时允许的时钟偏差，如 30s
+
+<token> 为 - 时从标准输入读取`
+
+func main() {
+	args, opts := command.ParseUsingDefaultAlgorithm(os.Args[1:]...)
+	if len(args) == 0 || hasOpt(opts, "h", "help") {
+		fmt.Println(usage)
+		return
+	}
+	var err error
+	switch args[0] {
+	case "jwt":
+		err = runJWT(args[1:], opts)
+	default:
+		err = usageError("unknown command %q", args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		if _, ok := err.(usageErr); ok {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// usageErr 参数错误，退出码为2
+type usageErr string
+
+func (e usageErr) Error() string {
+	return string(e)
+}
+
+func usageError(format string, args ...interface{}) error {
+	return usageErr(fmt.Sprintf(format, args...))
+}
+
+func hasOpt(opts map[string]string, names ...string) bool {
+	for _, name := range names {
+		if _, ok := opts[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/jwt"
+)
+
+// 上下文中保存认证信息的key
+const (
+	ClaimsKey = "claims"
+	RolesKey  = "roles"
+)
+
+// JWTAuthMiddleware 使用 jwt.ParseToken 校验 Authorization: Bearer 令牌，
+// 将claims保存到上下文 ClaimsKey，将 roles claim 解析后保存到 RolesKey
+func JWTAuthMiddleware(key string, skippers ...SkipperFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+		token := GetBearerToken(c)
+		if token == "" {
+			response.Abort(c, response.ErrUnauthorized.WithMessage("missing token"))
+			return
+		}
+		raw, ok := jwt.ParseTokenClaims(token, key)
+		if !ok {
+			response.Abort(c, response.ErrUnauthorized.WithMessage("invalid token"))
+			return
+		}
+		claims := make(map[string]string, len(raw))
+		for k, v := range raw {
+			claims[k] = fmt.Sprintf("%v", v)
+		}
+		c.Set(ClaimsKey, claims)
+		c.Set(RolesKey, parseRolesClaim(raw["roles"]))
+		c.Next()
+	}
+}
+
+// GetBearerToken 从 Authorization 请求头获取Bearer令牌
+func GetBearerToken(c *gin.Context) string {
+	auth := c.GetHeader("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// GetRoles 获取上下文中的角色列表
+func GetRoles(c *gin.Context) []string {
+	return c.GetStringSlice(RolesKey)
+}
+
+// parseRolesClaim 解析roles claim，支持JSON数组、JSON数组格式的字符串和逗号分隔的字符串
+func parseRolesClaim(v interface{}) []string {
+	var parts []string
+	switch v := v.(type) {
+	case []interface{}:
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+	case []string:
+		parts = v
+	case string:
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "[") {
+			if err := json.Unmarshal([]byte(v), &parts); err != nil {
+				return nil
+			}
+		} else {
+			parts = strings.Split(v, ",")
+		}
+	}
+	roles := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			roles = append(roles, p)
+		}
+	}
+	return roles
+}
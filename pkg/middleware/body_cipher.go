@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+)
+
+// NewAESGCMBodyCipher 使用 security.AESGCM 认证加密，密钥为base64编码，密文为base64字符串，推荐优先使用
+func NewAESGCMBodyCipher(b64Key string) (BodyCipher, error) {
+	g, err := security.NewAESGCMFromBase64(b64Key)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedBodyCipher{cipher: g.WithAAD(nil), encoding: base64.StdEncoding}, nil
+}
+
+// NewAESCBCBodyCipher 使用 security.AESCBC 加解密，密钥为base64编码，密文为base64字符串，与 security.AESCBCMode 兼容
+func NewAESCBCBodyCipher(b64Key string) (BodyCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(b64Key)
+	if err != nil {
+		return nil, err
+	}
+	c, err := security.NewAESCBC(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedBodyCipher{cipher: c, encoding: base64.StdEncoding}, nil
+}
+
+// NewAESCFBBodyCipher 使用 security.AESCFB 加解密，密文为hex字符串，与 security.AESCFBMode 兼容
+func NewAESCFBBodyCipher(key string) (BodyCipher, error) {
+	c, err := security.NewAESCFB([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return &encodedBodyCipher{cipher: c, encoding: security.HexEncoding}, nil
+}
+
+// encodedBodyCipher 将 security.Cipher 的二进制密文编码为文本
+type encodedBodyCipher struct {
+	cipher   security.Cipher
+	encoding security.TextEncoding
+}
+
+func (e *encodedBodyCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	ct, err := e.cipher.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(e.encoding.EncodeToString(ct)), nil
+}
+
+func (e *encodedBodyCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	ct, err := e.encoding.DecodeString(strings.TrimSpace(string(ciphertext)))
+	if err != nil {
+		return nil, err
+	}
+	return e.cipher.Decrypt(ct)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// BodyLimitMiddleware 限制请求体大小，超出时返回413
+// Content-Length已超出时直接拒绝，否则包装请求体，读取超过限制时返回 *http.MaxBytesError，
+// handler 使用 response.Fail 返回该错误时同样响应413
+func BodyLimitMiddleware(maxBytes int64, skippers ...SkipperFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			response.Abort(c, response.ErrTooLarge)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// ContentTypeMiddleware 限制带请求体的请求的Content-Type，不在允许列表中时返回415
+// 允许列表支持 type/* 通配，如 text/*
+func ContentTypeMiddleware(allowed []string, skippers ...SkipperFunc) gin.HandlerFunc {
+	types := make([]string, 0, len(allowed))
+	for _, t := range allowed {
+		types = append(types, strings.ToLower(strings.TrimSpace(t)))
+	}
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) || !hasRequestBody(c.Request) {
+			c.Next()
+			return
+		}
+		mt, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !matchMediaType(types, strings.ToLower(mt)) {
+			response.Abort(c, response.ErrUnsupportedType)
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasRequestBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	return r.ContentLength != 0 || len(r.TransferEncoding) > 0
+}
+
+func matchMediaType(allowed []string, mt string) bool {
+	for _, t := range allowed {
+		if t == mt || t == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/breaker"
+)
+
+// BreakerMiddleware 熔断中间件，响应状态码>=500或耗时超过慢调用阈值计为失败，熔断时返回503
+func BreakerMiddleware(b *breaker.Breaker, skippers ...SkipperFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+		done, err := b.Allow()
+		if err != nil {
+			response.Abort(c, response.ErrUnavailable.WithCause(err))
+			return
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				done(fmt.Errorf("panic: %v", r))
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		var reqErr error
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+			reqErr = fmt.Errorf("response status %d", status)
+		}
+		done(reqErr)
+	}
+}
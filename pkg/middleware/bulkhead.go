@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// BulkheadConfig 并发隔离配置
+type BulkheadConfig struct {
+	// MaxConcurrent 最大并发处理数
+	MaxConcurrent int
+	// MaxQueue 最大排队数，为0时并发已满直接拒绝
+	MaxQueue int
+	// WaitTimeout 排队最长等待时间，默认1秒
+	WaitTimeout time.Duration
+}
+
+// BulkheadMiddleware 并发隔离中间件，限制同时处理的请求数，排队超时或队列已满时返回503
+func BulkheadMiddleware(cfg BulkheadConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.MaxConcurrent <= 0 {
+		panic("middleware: BulkheadConfig.MaxConcurrent must be positive")
+	}
+	if cfg.WaitTimeout <= 0 {
+		cfg.WaitTimeout = time.Second
+	}
+	sem := make(chan struct{}, cfg.MaxConcurrent)
+	var queued int64
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			if atomic.AddInt64(&queued, 1) > int64(cfg.MaxQueue) {
+				atomic.AddInt64(&queued, -1)
+				response.Abort(c, response.ErrUnavailable.WithMessage("server is busy"))
+				return
+			}
+			timer := time.NewTimer(cfg.WaitTimeout)
+			select {
+			case sem <- struct{}{}:
+				timer.Stop()
+				atomic.AddInt64(&queued, -1)
+			case <-timer.C:
+				atomic.AddInt64(&queued, -1)
+				response.Abort(c, response.ErrUnavailable.WithMessage("server is busy"))
+				return
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				atomic.AddInt64(&queued, -1)
+				c.Abort()
+				return
+			}
+		}
+		defer func() { <-sem }()
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newBulkheadRouter 返回的 release 关闭后 /slow 才会返回，entered 在进入handler时收到通知
+func newBulkheadRouter(cfg BulkheadConfig) (r *gin.Engine, entered chan struct{}, release chan struct{}) {
+	gin.SetMode(gin.TestMode)
+	entered = make(chan struct{}, 10)
+	release = make(chan struct{})
+	r = gin.New()
+	r.Use(BulkheadMiddleware(cfg))
+	r.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	return r, entered, release
+}
+
+func serveAsync(r http.Handler) chan *httptest.ResponseRecorder {
+	out := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		out <- w
+	}()
+	return out
+}
+
+func TestBulkheadMiddlewareRejectsAtCapacity(t *testing.T) {
+	r, entered, release := newBulkheadRouter(BulkheadConfig{MaxConcurrent: 1})
+
+	first := serveAsync(r)
+	<-entered
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("second request status = %d, want 503", w.Code)
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want 200", w.Code)
+	}
+	// 第一个请求结束后释放名额
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("request after release status = %d, want 200", w.Code)
+	}
+}
+
+func TestBulkheadMiddlewareWaitTimeout(t *testing.T) {
+	r, entered, release := newBulkheadRouter(BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, WaitTimeout: 20 * time.Millisecond})
+
+	first := serveAsync(r)
+	<-entered
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("queued request status = %d, want 503 after wait timeout", w.Code)
+	}
+	close(release)
+	<-first
+}
@@ -0,0 +1,336 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// 压缩算法
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressConfig 响应压缩配置
+type CompressConfig struct {
+	// Level 压缩级别，gzip.HuffmanOnly 到 gzip.BestCompression，0 或超出范围时使用默认级别
+	Level int
+	// MinLength 小于该长度的响应不压缩，默认1024
+	MinLength int
+	// ExcludedContentTypes 不压缩的Content-Type前缀，默认排除图片、音视频和常见压缩格式
+	ExcludedContentTypes []string
+	// DecompressRequest 是否解压 Content-Encoding: gzip 的请求体
+	DecompressRequest bool
+	// MaxDecompressedSize 解压后请求体的最大长度，默认10MB
+	MaxDecompressedSize int64
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+	"application/octet-stream", "font/woff",
+}
+
+// DefaultCompressConfig 默认响应压缩配置
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{Level: gzip.DefaultCompression}
+}
+
+// CompressMiddleware gzip/deflate响应压缩中间件，deflate 按HTTP规范使用zlib格式
+func CompressMiddleware(cfg CompressConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.Level == gzip.NoCompression || cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+		cfg.Level = gzip.DefaultCompression
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 1024
+	}
+	if cfg.ExcludedContentTypes == nil {
+		cfg.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = 10 << 20
+	}
+	pools := newCompressorPools(cfg.Level)
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		if cfg.DecompressRequest && strings.EqualFold(c.GetHeader("Content-Encoding"), EncodingGzip) {
+			gr, err := gzip.NewReader(c.Request.Body)
+			if err != nil {
+				response.Abort(c, response.ErrBadRequest.WithMessage("invalid gzip request body").WithCause(err))
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, gr, cfg.MaxDecompressedSize)
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.Header.Del("Content-Length")
+			c.Request.ContentLength = -1
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead ||
+			strings.Contains(strings.ToLower(c.GetHeader("Connection")), "upgrade") {
+			c.Next()
+			return
+		}
+
+		// 追加而不是覆盖，保留CORS等中间件设置的 Vary: Origin
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			pools:          pools,
+			cfg:            &cfg,
+		}
+		c.Writer = cw
+		defer func() {
+			c.Writer = cw.ResponseWriter
+			// panic 时丢弃缓存且不提交响应头，由 RecoveryMiddleware 写出500
+			if r := recover(); r != nil {
+				cw.discard()
+				panic(r)
+			}
+			cw.close()
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding 根据Accept-Encoding选择压缩算法，优先gzip，q=0表示不接受
+func negotiateEncoding(accept string) string {
+	if accept == "" {
+		return ""
+	}
+	var gzipQ, deflateQ float64 = -1, -1
+	var anyQ float64 = -1
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseEncodingQ(part)
+		switch name {
+		case EncodingGzip, "x-gzip":
+			gzipQ = q
+		case EncodingDeflate:
+			deflateQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if deflateQ < 0 {
+		deflateQ = anyQ
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return EncodingGzip
+	case deflateQ > 0:
+		return EncodingDeflate
+	}
+	return ""
+}
+
+func parseEncodingQ(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return name, q
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type compressorPools struct {
+	gzip    sync.Pool
+	deflate sync.Pool
+}
+
+func newCompressorPools(level int) *compressorPools {
+	p := &compressorPools{}
+	p.gzip.New = func() interface{} {
+		w, err := gzip.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			w = gzip.NewWriter(io.Discard)
+		}
+		return w
+	}
+	p.deflate.New = func() interface{} {
+		w, err := zlib.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			w = zlib.NewWriter(io.Discard)
+		}
+		return w
+	}
+	return p
+}
+
+func (p *compressorPools) get(encoding string, w io.Writer) compressor {
+	var cw compressor
+	if encoding == EncodingGzip {
+		cw = p.gzip.Get().(*gzip.Writer)
+	} else {
+		cw = p.deflate.Get().(*zlib.Writer)
+	}
+	cw.Reset(w)
+	return cw
+}
+
+func (p *compressorPools) put(encoding string, cw compressor) {
+	cw.Reset(io.Discard)
+	if encoding == EncodingGzip {
+		p.gzip.Put(cw)
+	} else {
+		p.deflate.Put(cw)
+	}
+}
+
+// compressWriter 先缓存MinLength字节的响应，根据长度和Content-Type决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	pools    *compressorPools
+	cfg      *CompressConfig
+
+	buf        bytes.Buffer
+	decided    bool
+	compressed bool
+	writer     compressor
+}
+
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.compressed {
+			return w.writer.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf.Write(data)
+	if w.buf.Len() >= w.cfg.MinLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.buf.Len() > 0
+}
+
+// Flush 流式响应时立即决定是否压缩并刷新已压缩的数据
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.buf.Len() > 0)
+	}
+	if w.compressed {
+		w.writer.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.decided {
+		w.decide(false)
+	}
+	return w.ResponseWriter.Hijack()
+}
+
+// decide 决定是否压缩并写出缓存的数据
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	w.compressed = large && w.shouldCompress()
+	h := w.ResponseWriter.Header()
+	if w.compressed {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.writer = w.pools.get(w.encoding, w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	data := w.buf.Bytes()
+	w.buf.Reset()
+	var err error
+	if w.compressed {
+		_, err = w.writer.Write(data)
+	} else {
+		_, err = w.ResponseWriter.Write(data)
+	}
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := strings.ToLower(h.Get("Content-Type"))
+	if ct == "" {
+		ct = strings.ToLower(http.DetectContentType(w.buf.Bytes()))
+	}
+	for _, excluded := range w.cfg.ExcludedContentTypes {
+		if strings.HasPrefix(ct, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// close 请求结束时写出剩余数据并归还压缩器，没有写入数据时不提交响应头
+func (w *compressWriter) close() {
+	if !w.decided && w.buf.Len() > 0 {
+		w.decide(w.buf.Len() >= w.cfg.MinLength)
+	}
+	if w.compressed {
+		w.writer.Close()
+		w.pools.put(w.encoding, w.writer)
+		w.writer = nil
+		w.compressed = false
+	}
+}
+
+// discard 丢弃缓存的数据并归还压缩器，不写出任何内容
+func (w *compressWriter) discard() {
+	w.buf.Reset()
+	if w.compressed {
+		w.pools.put(w.encoding, w.writer)
+		w.writer = nil
+		w.compressed = false
+	}
+}
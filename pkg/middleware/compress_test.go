@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompressMiddlewarePanicReturns500(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RecoveryMiddleware(), CompressMiddleware(DefaultCompressConfig()))
+	r.GET("/panic", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding = %q, want none", enc)
+	}
+	if body := w.Body.String(); strings.Contains(body, "partial") || !strings.Contains(body, "50000") {
+		t.Errorf("body = %q, want error envelope only", body)
+	}
+}
+
+func TestCompressMiddlewareEmptyResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CompressMiddleware(DefaultCompressConfig()))
+	r.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/empty", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want 204 with empty body", w.Code, w.Body.String())
+	}
+}
+
+func compressedResponse(t *testing.T, cfg CompressConfig, encoding string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CompressMiddleware(cfg))
+	r.GET("/data", func(c *gin.Context) { c.Data(http.StatusOK, "text/plain", body) })
+
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressMiddlewareZeroLevelUsesDefault(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 5000)
+	w := compressedResponse(t, CompressConfig{}, "gzip", body)
+
+	if enc := w.Header().Get("Content-Encoding"); enc != EncodingGzip {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+	if w.Body.Len() >= len(body) {
+		t.Errorf("compressed body is %d bytes, not smaller than %d", w.Body.Len(), len(body))
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(gr); err != nil || !bytes.Equal(got, body) {
+		t.Errorf("gunzip = %d bytes, %v", len(got), err)
+	}
+}
+
+func TestCompressMiddlewareDeflateIsZlib(t *testing.T) {
+	body := bytes.Repeat([]byte("b"), 5000)
+	w := compressedResponse(t, DefaultCompressConfig(), "deflate", body)
+
+	if enc := w.Header().Get("Content-Encoding"); enc != EncodingDeflate {
+		t.Fatalf("Content-Encoding = %q, want deflate", enc)
+	}
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("deflate body is not zlib: %v", err)
+	}
+	if got, err := io.ReadAll(zr); err != nil || !bytes.Equal(got, body) {
+		t.Errorf("inflate = %d bytes, %v", len(got), err)
+	}
+}
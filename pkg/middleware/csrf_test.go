@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testCSRFKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newCSRFRouter(cfg CSRFConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg.Key = testCSRFKey
+	cfg.SessionID = func(c *gin.Context) string { return c.GetHeader("X-Session") }
+	r := gin.New()
+	r.Use(CSRFMiddleware(cfg))
+	r.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, GetCSRFToken(c)) })
+	r.POST("/submit", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+// csrfToken 以 session 身份请求页面，返回下发的令牌
+func csrfToken(t *testing.T, r http.Handler, session string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.Header.Set("X-Session", session)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Value != w.Body.String() {
+		t.Fatalf("GET /form = %d, cookies %v, body %q", w.Code, cookies, w.Body.String())
+	}
+	return cookies[0].Value
+}
+
+func csrfPost(r http.Handler, session, cookie, header string, edit func(req *http.Request)) int {
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-Session", session)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: CSRFTokenKey, Value: cookie})
+	}
+	if header != "" {
+		req.Header.Set("X-CSRF-Token", header)
+	}
+	req.Header.Set("Origin", "http://example.com")
+	if edit != nil {
+		edit(req)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestCSRFMiddlewareSessionBinding(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{})
+	alice := csrfToken(t, r, "alice")
+	bob := csrfToken(t, r, "bob")
+
+	tests := []struct {
+		name    string
+		session string
+		cookie  string
+		header  string
+		want    int
+	}{
+		{"own token", "alice", alice, alice, http.StatusOK},
+		{"token from another session", "alice", bob, bob, http.StatusForbidden},
+		{"cookie and header differ", "alice", alice, bob, http.StatusForbidden},
+		{"missing header", "alice", alice, "", http.StatusForbidden},
+		{"missing cookie", "alice", "", alice, http.StatusForbidden},
+		{"forged token", "alice", "AAAA.AAAA", "AAAA.AAAA", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csrfPost(r, tt.session, tt.cookie, tt.header, nil); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// 令牌在另一个会话中请求页面时会被替换
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.Header.Set("X-Session", "alice")
+	req.AddCookie(&http.Cookie{Name: CSRFTokenKey, Value: bob})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() == bob || len(w.Result().Cookies()) != 1 {
+		t.Error("token of another session was reused")
+	}
+}
+
+func TestCSRFMiddlewareOrigin(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{TrustedOrigins: []string{"https://admin.example.com/"}})
+	token := csrfToken(t, r, "alice")
+
+	tests := []struct {
+		name string
+		edit func(req *http.Request)
+		want int
+	}{
+		{"same origin", nil, http.StatusOK},
+		{"trusted origin", func(req *http.Request) { req.Header.Set("Origin", "https://admin.example.com") }, http.StatusOK},
+		{"origin mismatch", func(req *http.Request) { req.Header.Set("Origin", "http://evil.com") }, http.StatusForbidden},
+		{"trusted host with other scheme", func(req *http.Request) { req.Header.Set("Origin", "http://admin.example.com") }, http.StatusForbidden},
+		{"null origin", func(req *http.Request) { req.Header.Set("Origin", "null") }, http.StatusForbidden},
+		{"referer mismatch", func(req *http.Request) {
+			req.Header.Del("Origin")
+			req.Header.Set("Referer", "http://evil.com/page")
+		}, http.StatusForbidden},
+		{"same site referer", func(req *http.Request) {
+			req.Header.Del("Origin")
+			req.Header.Set("Referer", "http://example.com/page")
+		}, http.StatusOK},
+		{"no origin or referer", func(req *http.Request) { req.Header.Del("Origin") }, http.StatusOK},
+		{"no origin or referer over TLS", func(req *http.Request) {
+			req.Header.Del("Origin")
+			req.TLS = &tls.ConnectionState{}
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csrfPost(r, "alice", token, token, tt.edit); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareRequireOrigin(t *testing.T) {
+	r := newCSRFRouter(CSRFConfig{RequireOrigin: true})
+	token := csrfToken(t, r, "alice")
+
+	if got := csrfPost(r, "alice", token, token, nil); got != http.StatusOK {
+		t.Errorf("with origin: status = %d, want 200", got)
+	}
+	// 是否拒绝不依赖请求是否直接经过TLS，TLS在代理终止时 r.TLS 为nil
+	for _, viaTLS := range []bool{false, true} {
+		got := csrfPost(r, "alice", token, token, func(req *http.Request) {
+			req.Header.Del("Origin")
+			if viaTLS {
+				req.TLS = &tls.ConnectionState{}
+			}
+		})
+		if got != http.StatusForbidden {
+			t.Errorf("no origin, TLS %v: status = %d, want 403", viaTLS, got)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// EncryptedContentType 加密请求/响应体默认的Content-Type
+const EncryptedContentType = "application/x-encrypted"
+
+// BodyCipher 请求/响应体加解密，内置 NewAESGCMBodyCipher、NewAESCBCBodyCipher、NewAESCFBBodyCipher，
+// 新接入的客户端推荐使用带认证的AES-GCM
+type BodyCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// BodyCipherResolver 根据请求获取加解密对象，可按appID等区分密钥
+type BodyCipherResolver func(c *gin.Context) (BodyCipher, error)
+
+// EncryptBodyConfig 加密请求/响应体配置
+type EncryptBodyConfig struct {
+	Resolver BodyCipherResolver
+	// ContentType 加密内容的Content-Type，默认 application/x-encrypted
+	ContentType string
+	// PlainContentType 解密后交给handler的Content-Type，默认 application/json
+	PlainContentType string
+	// Required 为true时拒绝未加密的请求
+	Required bool
+}
+
+// EncryptBodyMiddleware 透明解密请求体、加密响应体
+// 请求Content-Type为加密类型时解密请求体，请求已加密或Accept包含加密类型时加密响应体
+func EncryptBodyMiddleware(cfg EncryptBodyConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.Resolver == nil {
+		panic("middleware: EncryptBodyConfig.Resolver is required")
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = EncryptedContentType
+	}
+	if cfg.PlainContentType == "" {
+		cfg.PlainContentType = "application/json"
+	}
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		reqEncrypted := mediaTypeIs(c.GetHeader("Content-Type"), cfg.ContentType)
+		respEncrypted := reqEncrypted || acceptsMediaType(c.GetHeader("Accept"), cfg.ContentType)
+		if !reqEncrypted && cfg.Required && c.Request.ContentLength != 0 {
+			response.Abort(c, response.ErrUnsupportedType.WithMessage("request body must be encrypted"))
+			return
+		}
+		if !reqEncrypted && !respEncrypted {
+			c.Next()
+			return
+		}
+
+		bc, err := cfg.Resolver(c)
+		if err != nil {
+			log.Warnf("resolve body cipher error: %s", err)
+			response.Abort(c, response.ErrBadRequest.WithMessage("invalid encryption key").WithCause(err))
+			return
+		}
+
+		if reqEncrypted {
+			data, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				response.Abort(c, response.ErrBadRequest.WithMessage("read request body error").WithCause(err))
+				return
+			}
+			var plain []byte
+			if len(bytes.TrimSpace(data)) > 0 {
+				plain, err = bc.Decrypt(bytes.TrimSpace(data))
+				if err != nil {
+					log.Warnf("decrypt request body error, path: %s, error: %s", c.Request.URL.Path, err)
+					response.Abort(c, response.ErrBadRequest.WithMessage("decrypt request body error").WithCause(err))
+					return
+				}
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(plain))
+			c.Request.ContentLength = int64(len(plain))
+			c.Request.Header.Set("Content-Length", strconv.Itoa(len(plain)))
+			c.Request.Header.Set("Content-Type", cfg.PlainContentType)
+		}
+
+		if !respEncrypted {
+			c.Next()
+			return
+		}
+
+		bw := newBufferedWriter(c.Writer)
+		c.Writer = bw
+		// panic 时也要还原，缓存的响应直接丢弃，由 RecoveryMiddleware 写出错误
+		defer func() { c.Writer = bw.ResponseWriter }()
+		c.Next()
+		c.Writer = bw.ResponseWriter
+
+		if bw.body.Len() == 0 {
+			bw.flush(nil)
+			return
+		}
+		out, err := bc.Encrypt(bw.body.Bytes())
+		if err != nil {
+			log.Errorf("encrypt response body error, path: %s, error: %s", c.Request.URL.Path, err)
+			bw.Header().Del("Content-Length")
+			response.Fail(c, response.ErrInternal.WithMessage("encrypt response body error").WithCause(err))
+			return
+		}
+		bw.Header().Set("Content-Type", cfg.ContentType)
+		bw.Header().Del("Content-Length")
+		bw.flush(out)
+	}
+}
+
+// mediaTypeIs 判断Content-Type是否为指定类型，忽略参数
+func mediaTypeIs(contentType, want string) bool {
+	if contentType == "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(mt, want)
+}
+
+// acceptsMediaType 判断Accept是否明确包含指定类型
+func acceptsMediaType(accept, want string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		if mediaTypeIs(strings.TrimSpace(part), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+)
+
+func newEncryptBodyRouter(t *testing.T) (*gin.Engine, BodyCipher) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	bc, err := NewAESGCMBodyCipher(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(RecoveryMiddleware(), EncryptBodyMiddleware(EncryptBodyConfig{
+		Resolver: func(*gin.Context) (BodyCipher, error) { return bc, nil },
+	}))
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "secret") })
+	r.GET("/panic", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	return r, bc
+}
+
+func TestEncryptBodyMiddlewareEncryptsResponse(t *testing.T) {
+	r, bc := newEncryptBodyRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("Accept", EncryptedContentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != EncryptedContentType {
+		t.Fatalf("response = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	pt, err := bc.Decrypt(w.Body.Bytes())
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("decrypt response = %q, %v", pt, err)
+	}
+}
+
+func TestEncryptBodyMiddlewarePanicReturns500(t *testing.T) {
+	r, _ := newEncryptBodyRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept", EncryptedContentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	if body := w.Body.String(); strings.Contains(body, "partial") || !strings.Contains(body, "50000") {
+		t.Errorf("body = %q, want error envelope only", body)
+	}
+}
+
+func TestAESGCMBodyCipher(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	bc, err := NewAESGCMBodyCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := bc.Encrypt([]byte(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := bc.Decrypt(append(ct, '\n')); err != nil || string(pt) != `{"a":1}` {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(string(ct))
+	raw[len(raw)-1] ^= 1
+	if _, err := bc.Decrypt([]byte(base64.StdEncoding.EncodeToString(raw))); !errors.Is(err, security.ErrAuthFailed) {
+		t.Errorf("tampered body: %v, want ErrAuthFailed", err)
+	}
+	other, _ := NewAESGCMBodyCipher(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if _, err := other.Decrypt(ct); !errors.Is(err, security.ErrAuthFailed) {
+		t.Errorf("wrong key: %v, want ErrAuthFailed", err)
+	}
+	if _, err := NewAESGCMBodyCipher(base64.StdEncoding.EncodeToString([]byte("short"))); !errors.Is(err, security.ErrInvalidKeySize) {
+		t.Errorf("short key: %v, want ErrInvalidKeySize", err)
+	}
+}
+
+func TestLegacyBodyCiphersCompatible(t *testing.T) {
+	key := "0123456789abcdef"
+	cfb, err := NewAESCFBBodyCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := cfb.Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt := security.NewAESCFBEncrypt(key).Decrypt(string(ct)); pt != "hello" {
+		t.Errorf("AESCFBMode.Decrypt(body cipher output) = %q, want hello", pt)
+	}
+
+	b64Key := base64.StdEncoding.EncodeToString([]byte(key))
+	cbc, err := NewAESCBCBodyCipher(b64Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := security.NewAESCBCEncrypt(b64Key, "")
+	if pt, err := cbc.Decrypt([]byte(legacy.Encrypt("hello"))); err != nil || string(pt) != "hello" {
+		t.Errorf("Decrypt(AESCBCMode output) = %q, %v, want hello", pt, err)
+	}
+}
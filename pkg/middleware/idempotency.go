@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// HeaderIdempotencyKey 幂等键请求头
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// IdempotencyRecord 幂等记录，保存首次请求的响应
+type IdempotencyRecord struct {
+	BodyHash  string
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+}
+
+// IdempotencyStore 幂等记录存储
+type IdempotencyStore interface {
+	// Begin key不存在时创建处理中的记录并返回 nil；key已存在时返回已有记录
+	Begin(key, bodyHash string, ttl time.Duration) (*IdempotencyRecord, error)
+	// Complete 保存处理完成后的响应
+	Complete(key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release 删除记录，处理失败时调用以允许客户端重试
+	Release(key string) error
+}
+
+// IdempotencyConfig 幂等中间件配置
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	// Header 幂等键请求头，默认 Idempotency-Key
+	Header string
+	// TTL 记录有效期，默认24小时
+	TTL time.Duration
+	// Methods 需要幂等处理的请求方法，默认 POST、PUT、PATCH、DELETE
+	Methods []string
+	// Principal 获取当前调用方标识，如用户ID，不同调用方的相同幂等键互不影响
+	Principal func(c *gin.Context) string
+}
+
+// IdempotencyMiddleware 幂等中间件
+// 相同幂等键的重复请求直接返回首次响应，首次请求处理中时返回409，请求体不同时返回422
+func IdempotencyMiddleware(cfg IdempotencyConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryIdempotencyStore()
+	}
+	if cfg.Header == "" {
+		cfg.Header = HeaderIdempotencyKey
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	return func(c *gin.Context) {
+		idemKey := c.GetHeader(cfg.Header)
+		if idemKey == "" || !containsString(cfg.Methods, c.Request.Method) || SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		body, err := readAndRestoreBody(c.Request)
+		if err != nil {
+			response.Abort(c, response.ErrBadRequest.WithMessage("read request body error").WithCause(err))
+			return
+		}
+		bodyHash := sha256.Sum256(body)
+		hash := hex.EncodeToString(bodyHash[:])
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		var principal string
+		if cfg.Principal != nil {
+			principal = cfg.Principal(c)
+		}
+		key := principal + "|" + JoinRouter(c.Request.Method, route) + "|" + idemKey
+
+		record, err := cfg.Store.Begin(key, hash, cfg.TTL)
+		if err != nil {
+			response.Abort(c, response.ErrInternal.WithCause(err))
+			return
+		}
+		if record != nil {
+			replayIdempotent(c, record, hash)
+			return
+		}
+
+		tw := &teeWriter{ResponseWriter: c.Writer}
+		c.Writer = tw
+		completed := false
+		defer func() {
+			c.Writer = tw.ResponseWriter
+			if !completed {
+				if err := cfg.Store.Release(key); err != nil {
+					log.Errorf("release idempotency key %s error: %s", key, err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := tw.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		err = cfg.Store.Complete(key, &IdempotencyRecord{
+			BodyHash:  hash,
+			Completed: true,
+			Status:    status,
+			Header:    tw.Header().Clone(),
+			Body:      append([]byte(nil), tw.body.Bytes()...),
+		}, cfg.TTL)
+		if err != nil {
+			log.Errorf("save idempotency key %s error: %s", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replayIdempotent(c *gin.Context, record *IdempotencyRecord, bodyHash string) {
+	if record.BodyHash != bodyHash {
+		response.Abort(c, response.ErrUnprocessable.WithMessage("idempotency key reused with a different request body"))
+		return
+	}
+	if !record.Completed {
+		response.Abort(c, response.ErrConflict.WithMessage("request with the same idempotency key is in progress"))
+		return
+	}
+	h := c.Writer.Header()
+	for k, vs := range record.Header {
+		h[k] = append([]string(nil), vs...)
+	}
+	h.Set("Idempotent-Replayed", "true")
+	c.Writer.WriteHeader(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// ErrIdempotencyRecordNotFound 幂等记录不存在
+var ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+
+type memoryIdempotencyItem struct {
+	record   IdempotencyRecord
+	expireAt time.Time
+}
+
+// MemoryIdempotencyStore 内存幂等记录存储，仅适用于单实例部署
+type MemoryIdempotencyStore struct {
+	mu     sync.Mutex
+	items  map[string]*memoryIdempotencyItem
+	lastGC time.Time
+}
+
+// NewMemoryIdempotencyStore 创建内存幂等记录存储
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		items:  make(map[string]*memoryIdempotencyItem),
+		lastGC: time.Now(),
+	}
+}
+
+// Begin key不存在或已过期时创建处理中记录
+func (s *MemoryIdempotencyStore) Begin(key, bodyHash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastGC) > time.Minute {
+		for k, item := range s.items {
+			if now.After(item.expireAt) {
+				delete(s.items, k)
+			}
+		}
+		s.lastGC = now
+	}
+	if item, ok := s.items[key]; ok && now.Before(item.expireAt) {
+		record := item.record
+		return &record, nil
+	}
+	s.items[key] = &memoryIdempotencyItem{
+		record:   IdempotencyRecord{BodyHash: bodyHash},
+		expireAt: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete 保存处理完成后的响应
+func (s *MemoryIdempotencyStore) Complete(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[key]; !ok {
+		return ErrIdempotencyRecordNotFound
+	}
+	s.items[key] = &memoryIdempotencyItem{record: *record, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release 删除记录
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
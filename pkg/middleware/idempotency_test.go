@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type idempotencyRouter struct {
+	*gin.Engine
+	calls   int32
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newIdempotencyRouter() *idempotencyRouter {
+	gin.SetMode(gin.TestMode)
+	r := &idempotencyRouter{Engine: gin.New(), entered: make(chan struct{}, 1), release: make(chan struct{})}
+	r.Use(RecoveryMiddleware(), IdempotencyMiddleware(IdempotencyConfig{
+		Principal: func(c *gin.Context) string { return c.GetHeader("X-User") },
+	}))
+	r.POST("/orders", func(c *gin.Context) {
+		n := atomic.AddInt32(&r.calls, 1)
+		c.Header("X-Order", fmt.Sprint(n))
+		c.String(http.StatusCreated, "order %d", n)
+	})
+	r.POST("/slow", func(c *gin.Context) {
+		r.entered <- struct{}{}
+		<-r.release
+		c.Status(http.StatusOK)
+	})
+	r.POST("/fail", func(c *gin.Context) {
+		if atomic.AddInt32(&r.calls, 1) == 1 {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.POST("/panic", func(c *gin.Context) {
+		if atomic.AddInt32(&r.calls, 1) == 1 {
+			panic("boom")
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func idempotentRequest(path, key, user, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(HeaderIdempotencyKey, key)
+	req.Header.Set("X-User", user)
+	return req
+}
+
+func TestIdempotencyMiddlewareReplay(t *testing.T) {
+	r := newIdempotencyRouter()
+
+	first := serve(r, idempotentRequest("/orders", "k1", "alice", `{"a":1}`))
+	if first.Code != http.StatusCreated || first.Body.String() != "order 1" {
+		t.Fatalf("first response = %d %q", first.Code, first.Body.String())
+	}
+	replay := serve(r, idempotentRequest("/orders", "k1", "alice", `{"a":1}`))
+	if replay.Code != http.StatusCreated || replay.Body.String() != "order 1" ||
+		replay.Header().Get("X-Order") != "1" || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replayed response = %d %q %v", replay.Code, replay.Body.String(), replay.Header())
+	}
+	if n := atomic.LoadInt32(&r.calls); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+
+	// 不同调用方、不同幂等键和没有幂等键的请求都正常处理
+	serve(r, idempotentRequest("/orders", "k1", "bob", `{"a":1}`))
+	serve(r, idempotentRequest("/orders", "k2", "alice", `{"a":1}`))
+	serve(r, idempotentRequest("/orders", "", "alice", `{"a":1}`))
+	if n := atomic.LoadInt32(&r.calls); n != 4 {
+		t.Errorf("handler called %d times, want 4", n)
+	}
+}
+
+func TestIdempotencyMiddlewareDifferentBody(t *testing.T) {
+	r := newIdempotencyRouter()
+	serve(r, idempotentRequest("/orders", "k1", "alice", `{"a":1}`))
+
+	w := serve(r, idempotentRequest("/orders", "k1", "alice", `{"a":2}`))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", w.Code)
+	}
+	if n := atomic.LoadInt32(&r.calls); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	r := newIdempotencyRouter()
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- serve(r, idempotentRequest("/slow", "k1", "alice", "{}")) }()
+	<-r.entered
+
+	if w := serve(r, idempotentRequest("/slow", "k1", "alice", "{}")); w.Code != http.StatusConflict {
+		t.Errorf("concurrent request status = %d, want 409", w.Code)
+	}
+	close(r.release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want 200", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesOnFailure(t *testing.T) {
+	for _, path := range []string{"/fail", "/panic"} {
+		t.Run(path, func(t *testing.T) {
+			r := newIdempotencyRouter()
+			if w := serve(r, idempotentRequest(path, "k1", "alice", "{}")); w.Code < http.StatusInternalServerError {
+				t.Fatalf("first request status = %d, want 5xx", w.Code)
+			}
+			// 失败的请求不保存结果，客户端可以用相同的幂等键重试
+			if w := serve(r, idempotentRequest(path, "k1", "alice", "{}")); w.Code != http.StatusOK {
+				t.Errorf("retry status = %d, want 200", w.Code)
+			}
+			if n := atomic.LoadInt32(&r.calls); n != 2 {
+				t.Errorf("handler called %d times, want 2", n)
+			}
+		})
+	}
+}
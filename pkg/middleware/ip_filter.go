@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// IPFilterConfig IP黑白名单配置，支持单个IP和CIDR，IPv4和IPv6
+type IPFilterConfig struct {
+	// Allow 白名单，为空时允许所有未被黑名单拒绝的IP
+	Allow []string
+	// Deny 黑名单，优先于白名单
+	Deny []string
+	// TrustedProxies 可信代理，只有直连地址为可信代理时才读取 X-Forwarded-For/X-Real-IP
+	TrustedProxies []string
+}
+
+// IPFilter IP黑白名单过滤，名单可热更新
+type IPFilter struct {
+	mu      sync.RWMutex
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	proxies []netip.Prefix
+}
+
+// NewIPFilter 创建IP过滤器
+func NewIPFilter(cfg IPFilterConfig) (*IPFilter, error) {
+	f := &IPFilter{}
+	if err := f.Update(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update 热更新黑白名单和可信代理，解析失败时保持原配置不变
+func (f *IPFilter) Update(cfg IPFilterConfig) error {
+	allow, err := parsePrefixes(cfg.Allow)
+	if err != nil {
+		return err
+	}
+	deny, err := parsePrefixes(cfg.Deny)
+	if err != nil {
+		return err
+	}
+	proxies, err := parsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.allow, f.deny, f.proxies = allow, deny, proxies
+	f.mu.Unlock()
+	return nil
+}
+
+// Allowed 判断IP是否允许访问
+func (f *IPFilter) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if prefixesContain(f.deny, addr) {
+		return false
+	}
+	return len(f.allow) == 0 || prefixesContain(f.allow, addr)
+}
+
+// ClientIP 获取真实客户端IP，只有直连地址为可信代理时才信任转发头
+func (f *IPFilter) ClientIP(r *http.Request) string {
+	peer := stripPort(r.RemoteAddr)
+	peerAddr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return peer
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !prefixesContain(f.proxies, peerAddr.Unmap()) {
+		return peer
+	}
+
+	// 从右向左查找第一个非可信代理的地址
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		ips := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(ips[i]))
+			if err != nil {
+				break
+			}
+			if !prefixesContain(f.proxies, addr.Unmap()) {
+				return addr.Unmap().String()
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if addr, err := netip.ParseAddr(realIP); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	return peer
+}
+
+// Middleware 返回IP过滤中间件
+func (f *IPFilter) Middleware(skippers ...SkipperFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+		ip := f.ClientIP(c.Request)
+		if !f.Allowed(ip) {
+			log.Warnf("ip %s is not allowed to access %s", ip, c.Request.URL.Path)
+			response.Abort(c, response.ErrForbidden.WithMessage("ip not allowed"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// IPFilterMiddleware 根据配置创建IP过滤中间件，配置错误时panic
+func IPFilterMiddleware(cfg IPFilterConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	f, err := NewIPFilter(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return f.Middleware(skippers...)
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q: %w", s, err)
+			}
+			if p.Addr().Is4In6() && p.Bits() >= 96 {
+				p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %q: %w", s, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilterClientIP(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{TrustedProxies: []string{"10.0.0.0/8", "::ffff:192.168.1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"spoofed xff from untrusted peer", "203.0.113.7:5000", []string{"1.2.3.4"}, "", "203.0.113.7"},
+		{"spoofed x-real-ip from untrusted peer", "203.0.113.7:5000", nil, "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:80", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"client prepends a fake address", "10.0.0.1:80", []string{"1.2.3.4, 198.51.100.9"}, "", "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.1:80", []string{"198.51.100.9, 10.1.1.1", "10.2.2.2"}, "", "198.51.100.9"},
+		{"ipv4-mapped trusted proxy", "[::ffff:192.168.1.1]:80", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"garbage in chain stops the walk", "10.0.0.1:80", []string{"198.51.100.9, bogus, 10.1.1.1"}, "", "10.0.0.1"},
+		{"x-real-ip from trusted proxy", "10.0.0.1:80", nil, "198.51.100.9", "198.51.100.9"},
+		{"all hops trusted", "10.0.0.1:80", []string{"10.1.1.1"}, "", "10.0.0.1"},
+		{"ipv6 client", "10.0.0.1:80", []string{"2001:db8::1"}, "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := f.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPFilterAllowed(t *testing.T) {
+	f, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"192.168.0.0/16", "2001:db8::/32"},
+		Deny:  []string{"192.168.1.100"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{
+		"192.168.2.3":        true,
+		"::ffff:192.168.2.3": true,
+		"192.168.1.100":      false,
+		"10.0.0.1":           false,
+		"2001:db8::5":        true,
+		"2001:db9::5":        false,
+		"not-an-ip":          false,
+	} {
+		if got := f.Allowed(ip); got != want {
+			t.Errorf("Allowed(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	if err := f.Update(IPFilterConfig{Deny: []string{"bad/cidr"}}); err == nil {
+		t.Fatal("Update accepted an invalid cidr")
+	}
+	if !f.Allowed("192.168.2.3") {
+		t.Error("failed Update changed the filter")
+	}
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(IPFilterMiddleware(IPFilterConfig{Deny: []string{"198.51.100.9"}, TrustedProxies: []string{"10.0.0.1"}}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   int
+	}{
+		{"denied client behind trusted proxy", "10.0.0.1:80", "198.51.100.9", http.StatusForbidden},
+		{"denied client cannot hide behind spoofed xff", "198.51.100.9:80", "203.0.113.7", http.StatusForbidden},
+		{"allowed client", "203.0.113.7:80", "198.51.100.9", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			if w := serve(r, req); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// LoggerMiddleware 访问日志中间件，记录请求方法、路径、状态码、业务码和耗时
+func LoggerMiddleware(skippers ...SkipperFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		code := response.CodeSuccess
+		var errMsg string
+		if e := response.GetError(c); e != nil {
+			code = e.Code
+			errMsg = e.Error()
+		}
+		switch {
+		case status >= 500:
+			log.Errorf("[ACCESS] %s %s %d code=%d latency=%s ip=%s error=%s",
+				c.Request.Method, c.Request.URL.Path, status, code, latency, c.ClientIP(), errMsg)
+		case status >= 400:
+			log.Warnf("[ACCESS] %s %s %d code=%d latency=%s ip=%s error=%s",
+				c.Request.Method, c.Request.URL.Path, status, code, latency, c.ClientIP(), errMsg)
+		default:
+			log.Infof("[ACCESS] %s %s %d code=%d latency=%s ip=%s",
+				c.Request.Method, c.Request.URL.Path, status, code, latency, c.ClientIP())
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
 	"io/ioutil"
 	"runtime"
 
@@ -24,9 +25,8 @@ func RecoveryMiddleware() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				stack := stack(3)
 
-				log.Errorf("页面报错", string(stack)) //这里会打印出错栈信息
-				c.JSON(500, gin.H{"message": "Recovery"})
-				c.Abort()
+				log.Errorf("页面报错: %v\n%s", err, string(stack)) //这里会打印出错栈信息
+				response.Abort(c, response.ErrInternal.WithCause(fmt.Errorf("panic: %v", err)))
 				return
 			}
 		}()
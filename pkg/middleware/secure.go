@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// CSPNonceKey 上下文中保存CSP nonce的key
+const CSPNonceKey = "csp_nonce"
+
+// cspNoncePlaceholder CSP策略中的nonce占位符，如 script-src 'self' 'nonce-{nonce}'
+const cspNoncePlaceholder = "{nonce}"
+
+// SecureHeadersConfig 安全响应头配置
+type SecureHeadersConfig struct {
+	// HSTSMaxAge HSTS有效期(秒)，为0时不设置
+	HSTSMaxAge           int
+	HSTSIncludeSubdomain bool
+	HSTSPreload          bool
+	// ContentSecurityPolicy 可包含 {nonce} 占位符，每个请求生成新的nonce
+	ContentSecurityPolicy string
+	// FrameOptions 默认 DENY
+	FrameOptions string
+	// ContentTypeNosniff 设置 X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+	ReferrerPolicy     string
+	PermissionsPolicy  string
+	// SSLRedirect 非HTTPS请求重定向到HTTPS
+	SSLRedirect bool
+	// SSLHost 重定向使用的host，为空时使用请求的host
+	SSLHost string
+	// SSLProxyHeaders 反向代理设置的协议头，如 X-Forwarded-Proto: https
+	SSLProxyHeaders map[string]string
+	// AllowedHosts 允许的Host列表，为空时不检查
+	AllowedHosts []string
+}
+
+// DefaultSecureHeadersConfig 默认安全响应头配置
+func DefaultSecureHeadersConfig() SecureHeadersConfig {
+	return SecureHeadersConfig{
+		HSTSMaxAge:           31536000,
+		HSTSIncludeSubdomain: true,
+		FrameOptions:         "DENY",
+		ContentTypeNosniff:   true,
+		ReferrerPolicy:       "strict-origin-when-cross-origin",
+		SSLProxyHeaders:      map[string]string{"X-Forwarded-Proto": "https"},
+	}
+}
+
+// SecureHeaders 安全响应头中间件
+func SecureHeaders(cfg SecureHeadersConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomain {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	allowedHosts := make(map[string]struct{}, len(cfg.AllowedHosts))
+	for _, h := range cfg.AllowedHosts {
+		allowedHosts[strings.ToLower(h)] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		if len(allowedHosts) > 0 {
+			if _, ok := allowedHosts[strings.ToLower(stripPort(c.Request.Host))]; !ok {
+				response.Abort(c, response.ErrBadRequest.WithMessage("host not allowed"))
+				return
+			}
+		}
+
+		isSSL := isSSLRequest(c.Request, cfg.SSLProxyHeaders)
+		if cfg.SSLRedirect && !isSSL {
+			host := cfg.SSLHost
+			if host == "" {
+				host = c.Request.Host
+			}
+			code := http.StatusMovedPermanently
+			if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+			c.Redirect(code, "https://"+host+c.Request.URL.RequestURI())
+			c.Abort()
+			return
+		}
+
+		h := c.Writer.Header()
+		if hsts != "" && isSSL {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if cfg.ContentSecurityPolicy != "" {
+			csp := cfg.ContentSecurityPolicy
+			if strings.Contains(csp, cspNoncePlaceholder) {
+				nonce, err := cspNonce()
+				if err != nil {
+					response.Abort(c, response.ErrInternal.WithCause(err))
+					return
+				}
+				c.Set(CSPNonceKey, nonce)
+				csp = strings.ReplaceAll(csp, cspNoncePlaceholder, nonce)
+			}
+			h.Set("Content-Security-Policy", csp)
+		}
+		frameOptions := cfg.FrameOptions
+		if frameOptions == "" {
+			frameOptions = "DENY"
+		}
+		h.Set("X-Frame-Options", frameOptions)
+		if cfg.ContentTypeNosniff {
+			h.Set("X-Content-Type-Options", "nosniff")
+		}
+		if cfg.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.PermissionsPolicy != "" {
+			h.Set("Permissions-Policy", cfg.PermissionsPolicy)
+		}
+		c.Next()
+	}
+}
+
+// GetCSPNonce 获取当前请求的CSP nonce，用于模板中的 <script nonce="...">
+func GetCSPNonce(c *gin.Context) string {
+	return c.GetString(CSPNonceKey)
+}
+
+func cspNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func isSSLRequest(r *http.Request, proxyHeaders map[string]string) bool {
+	if r.TLS != nil || strings.EqualFold(r.URL.Scheme, "https") {
+		return true
+	}
+	for k, v := range proxyHeaders {
+		if strings.EqualFold(r.Header.Get(k), v) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
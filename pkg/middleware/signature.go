@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/utils"
+)
+
+// 签名相关请求头
+const (
+	HeaderAppID     = "X-App-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+	HeaderSignType  = "X-Sign-Type"
+)
+
+// 签名算法
+const (
+	SignTypeHMACSHA256    = "HMAC-SHA256"
+	SignTypeSHA256WithRSA = "SHA256withRSA"
+)
+
+var (
+	ErrSignMissingHeader = errors.New("signature headers missing")
+	ErrSignTimestamp     = errors.New("signature timestamp invalid or expired")
+	ErrSignNonceReplay   = errors.New("signature nonce already used")
+	ErrSignType          = errors.New("signature type not supported")
+	ErrSignMismatch      = errors.New("signature mismatch")
+	ErrSignKey           = errors.New("signature key unavailable")
+)
+
+// SignKeyResolver 根据appID获取验签密钥，HMAC为共享密钥，RSA为PEM格式公钥
+type SignKeyResolver func(appID, signType string) (string, error)
+
+// NonceStore 随机串存储，用于防重放
+type NonceStore interface {
+	// Use 标记nonce已使用，如果已经使用过则返回false
+	Use(nonce string, ttl time.Duration) bool
+}
+
+// SignatureConfig 签名验证配置
+type SignatureConfig struct {
+	KeyResolver SignKeyResolver
+	// MaxSkew 允许的时间偏差，默认5分钟
+	MaxSkew time.Duration
+	// NonceStore 默认使用内存存储
+	NonceStore NonceStore
+	// SignTypes 允许的签名算法，默认全部允许
+	SignTypes []string
+}
+
+// SignatureMiddleware 请求签名验证中间件
+func SignatureMiddleware(cfg SignatureConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.KeyResolver == nil {
+		panic("middleware: SignatureConfig.KeyResolver is required")
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.NonceStore == nil {
+		cfg.NonceStore = NewMemoryNonceStore()
+	}
+	if len(cfg.SignTypes) == 0 {
+		cfg.SignTypes = []string{SignTypeHMACSHA256, SignTypeSHA256WithRSA}
+	}
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+		if err := verifyRequestSign(c, &cfg); err != nil {
+			log.Warnf("request signature verify failed, path: %s, error: %s", c.Request.URL.Path, err)
+			var e *response.Error
+			if errors.As(err, &e) {
+				response.Abort(c, e)
+				return
+			}
+			response.Abort(c, response.ErrUnauthorized.WithMessage(err.Error()).WithCause(err))
+			return
+		}
+		c.Next()
+	}
+}
+
+func verifyRequestSign(c *gin.Context, cfg *SignatureConfig) error {
+	h := c.Request.Header
+	appID, ts, nonce, sign := h.Get(HeaderAppID), h.Get(HeaderTimestamp), h.Get(HeaderNonce), h.Get(HeaderSignature)
+	if appID == "" || ts == "" || nonce == "" || sign == "" {
+		return ErrSignMissingHeader
+	}
+	signType := h.Get(HeaderSignType)
+	if signType == "" {
+		signType = SignTypeHMACSHA256
+	}
+	if !containsString(cfg.SignTypes, signType) {
+		return ErrSignType
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrSignTimestamp
+	}
+	if d := time.Since(time.Unix(unix, 0)); d > cfg.MaxSkew || d < -cfg.MaxSkew {
+		return ErrSignTimestamp
+	}
+
+	// 读取请求体失败时按错误类型响应，超出 BodyLimitMiddleware 限制时为413
+	body, err := readAndRestoreBody(c.Request)
+	if err != nil {
+		return response.FromError(err)
+	}
+	// 密钥查询的错误信息只记录日志，不返回给客户端
+	key, err := cfg.KeyResolver(appID, signType)
+	if err != nil {
+		return response.ErrUnauthorized.WithMessage(ErrSignKey.Error()).WithCause(err)
+	}
+	canonical := CanonicalRequest(c.Request.Method, c.Request.URL, body, ts, nonce)
+	switch signType {
+	case SignTypeHMACSHA256:
+		err = security.VerifyHMACSHA256Base64(canonical, []byte(key), sign)
+	case SignTypeSHA256WithRSA:
+		err = security.VerifySHA256WithRSABase64(canonical, sign, key)
+	default:
+		return ErrSignType
+	}
+	if err != nil {
+		return ErrSignMismatch
+	}
+
+	// 验签通过后再记录nonce，避免伪造请求占用nonce
+	if !cfg.NonceStore.Use(appID+":"+nonce, 2*cfg.MaxSkew) {
+		return ErrSignNonceReplay
+	}
+	return nil
+}
+
+// CanonicalRequest 生成待签名字符串：method、path、排序后的query、body的sha256、时间戳、随机串，以换行分隔
+func CanonicalRequest(method string, u *url.URL, body []byte, timestamp, nonce string) []byte {
+	bodyHash := sha256.Sum256(body)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return []byte(strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		canonicalQuery(u.Query()),
+		hex.EncodeToString(bodyHash[:]),
+		timestamp,
+		nonce,
+	}, "\n"))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k))
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(v))
+		}
+	}
+	return buf.String()
+}
+
+func readAndRestoreBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestSigner 客户端请求签名
+type RequestSigner struct {
+	AppID    string
+	SignType string
+	// Key HMAC为共享密钥，RSA为PEM格式私钥
+	Key string
+}
+
+// Sign 为请求添加签名相关请求头
+func (s *RequestSigner) Sign(req *http.Request) error {
+	signType := s.SignType
+	if signType == "" {
+		signType = SignTypeHMACSHA256
+	}
+	body, err := readAndRestoreBody(req)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := utils.SignKey()
+	canonical := CanonicalRequest(req.Method, req.URL, body, ts, nonce)
+
+	var sign string
+	switch signType {
+	case SignTypeHMACSHA256:
+		sign = security.HMACSHA256Base64(canonical, []byte(s.Key))
+	case SignTypeSHA256WithRSA:
+		sign, err = security.SHA256WithRSABase64(canonical, []byte(s.Key))
+		if err != nil {
+			return fmt.Errorf("sign request error: %w", err)
+		}
+	default:
+		return ErrSignType
+	}
+
+	req.Header.Set(HeaderAppID, s.AppID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignType, signType)
+	req.Header.Set(HeaderSignature, sign)
+	return nil
+}
+
+// MemoryNonceStore 内存随机串存储
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	items   map[string]time.Time
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// NewMemoryNonceStore 创建内存随机串存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		items:   make(map[string]time.Time),
+		lastGC:  time.Now(),
+		gcEvery: time.Minute,
+	}
+}
+
+// Use 标记nonce已使用，如果在有效期内已使用过则返回false
+func (s *MemoryNonceStore) Use(nonce string, ttl time.Duration) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastGC) > s.gcEvery {
+		for k, exp := range s.items {
+			if now.After(exp) {
+				delete(s.items, k)
+			}
+		}
+		s.lastGC = now
+	}
+	if exp, ok := s.items[nonce]; ok && now.Before(exp) {
+		return false
+	}
+	s.items[nonce] = now.Add(ttl)
+	return true
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+)
+
+const testSignSecret = "app-secret"
+
+func newSignatureRouter(t *testing.T, rsaPub string, extra ...gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(extra...)
+	r.Use(SignatureMiddleware(SignatureConfig{
+		KeyResolver: func(appID, signType string) (string, error) {
+			switch {
+			case appID != "app1":
+				return "", errors.New("db: app " + appID + " not found")
+			case signType == SignTypeSHA256WithRSA:
+				return rsaPub, nil
+			}
+			return testSignSecret, nil
+		},
+	}))
+	r.POST("/orders", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	})
+	return r
+}
+
+func signedRequest(t *testing.T, signer *RequestSigner, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/orders?b=2&a=1", strings.NewReader(body))
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func serve(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSignatureMiddlewareValid(t *testing.T) {
+	priv, pub, err := security.GenerateRSAKeysPem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newSignatureRouter(t, pub)
+
+	for _, signer := range []*RequestSigner{
+		{AppID: "app1", Key: testSignSecret},
+		{AppID: "app1", SignType: SignTypeSHA256WithRSA, Key: priv},
+	} {
+		w := serve(r, signedRequest(t, signer, `{"amount":1}`))
+		if w.Code != http.StatusOK || w.Body.String() != `{"amount":1}` {
+			t.Errorf("%s: response = %d %q, want 200 with the original body", signer.SignType, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSignatureMiddlewareRejects(t *testing.T) {
+	r := newSignatureRouter(t, "")
+	signer := &RequestSigner{AppID: "app1", Key: testSignSecret}
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+	}{
+		{"bad signature", func() *http.Request {
+			return signedRequest(t, &RequestSigner{AppID: "app1", Key: "wrong-secret"}, "{}")
+		}},
+		{"tampered body", func() *http.Request {
+			req := signedRequest(t, signer, `{"amount":1}`)
+			req.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount":9}`)).Body
+			return req
+		}},
+		{"tampered query", func() *http.Request {
+			req := signedRequest(t, signer, "{}")
+			req.URL.RawQuery = "a=1&b=3"
+			return req
+		}},
+		{"missing signature", func() *http.Request {
+			req := signedRequest(t, signer, "{}")
+			req.Header.Del(HeaderSignature)
+			return req
+		}},
+		{"timestamp too old", func() *http.Request {
+			req := signedRequest(t, signer, "{}")
+			req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-6*time.Minute).Unix(), 10))
+			return req
+		}},
+		{"timestamp in the future", func() *http.Request {
+			req := signedRequest(t, signer, "{}")
+			req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(6*time.Minute).Unix(), 10))
+			return req
+		}},
+		{"unsupported sign type", func() *http.Request {
+			req := signedRequest(t, signer, "{}")
+			req.Header.Set(HeaderSignType, "MD5")
+			return req
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, tt.req()); w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestSignatureMiddlewareNonceReplay(t *testing.T) {
+	r := newSignatureRouter(t, "")
+	req := signedRequest(t, &RequestSigner{AppID: "app1", Key: testSignSecret}, "{}")
+	replay := req.Clone(req.Context())
+	replay.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")).Body
+
+	if w := serve(r, req); w.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", w.Code)
+	}
+	w := serve(r, replay)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), ErrSignNonceReplay.Error()) {
+		t.Errorf("replayed request = %d %q, want 401 nonce replay", w.Code, w.Body.String())
+	}
+}
+
+func TestSignatureMiddlewareKeyError(t *testing.T) {
+	r := newSignatureRouter(t, "")
+	w := serve(r, signedRequest(t, &RequestSigner{AppID: "app2", Key: testSignSecret}, "{}"))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	// 密钥查询错误只记录日志，不返回给客户端
+	if body := w.Body.String(); strings.Contains(body, "db:") || !strings.Contains(body, ErrSignKey.Error()) {
+		t.Errorf("body = %q, want only the generic key error", body)
+	}
+}
+
+func TestSignatureMiddlewareBodyTooLarge(t *testing.T) {
+	r := newSignatureRouter(t, "", BodyLimitMiddleware(16))
+	req := signedRequest(t, &RequestSigner{AppID: "app1", Key: testSignSecret}, strings.Repeat("x", 64))
+	// 未知长度的请求体在验签读取时才超出限制
+	req.ContentLength = -1
+	w := serve(r, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
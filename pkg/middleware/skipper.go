@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"net/http"
+	stdpath "path"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SkipHandler 统一处理跳过函数，任意一个跳过函数返回true则跳过
+func SkipHandler(c *gin.Context, skippers ...SkipperFunc) bool {
+	for _, skipper := range skippers {
+		if skipper != nil && skipper(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skippable 包装任意中间件，使其支持跳过
+func Skippable(skipper SkipperFunc, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHandler(c, skipper) {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// And 所有跳过函数都返回true时才跳过
+func And(skippers ...SkipperFunc) SkipperFunc {
+	return func(c *gin.Context) bool {
+		for _, skipper := range skippers {
+			if skipper == nil || !skipper(c) {
+				return false
+			}
+		}
+		return len(skippers) > 0
+	}
+}
+
+// Or 任意一个跳过函数返回true即跳过
+func Or(skippers ...SkipperFunc) SkipperFunc {
+	return func(c *gin.Context) bool {
+		return SkipHandler(c, skippers...)
+	}
+}
+
+// Not 对跳过函数的结果取反
+func Not(skipper SkipperFunc) SkipperFunc {
+	return func(c *gin.Context) bool {
+		return !SkipHandler(c, skipper)
+	}
+}
+
+// PathPrefixSkipper 按路径段匹配前缀，/api 只匹配 /api 和 /api/...，不匹配 /apiv2
+func PathPrefixSkipper(prefixes ...string) SkipperFunc {
+	return func(c *gin.Context) bool {
+		path := c.Request.URL.Path
+		for _, p := range prefixes {
+			if hasPathPrefix(path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathGlobSkipper 按通配符匹配路径，* 匹配单个路径段，** 匹配任意多个路径段
+func PathGlobSkipper(patterns ...string) SkipperFunc {
+	return func(c *gin.Context) bool {
+		path := c.Request.URL.Path
+		for _, p := range patterns {
+			if MatchPathGlob(p, path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathRegexpSkipper 按正则表达式匹配路径，表达式非法时panic
+func PathRegexpSkipper(exprs ...string) SkipperFunc {
+	regs := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		regs = append(regs, regexp.MustCompile(expr))
+	}
+	return func(c *gin.Context) bool {
+		path := c.Request.URL.Path
+		for _, reg := range regs {
+			if reg.MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MethodSkipper 请求方法匹配时跳过
+func MethodSkipper(methods ...string) SkipperFunc {
+	return func(c *gin.Context) bool {
+		for _, m := range methods {
+			if strings.EqualFold(c.Request.Method, m) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RouteSkipper 按gin路由模板匹配(c.FullPath())，如 /users/:id
+func RouteSkipper(routes ...string) SkipperFunc {
+	return func(c *gin.Context) bool {
+		fullPath := c.FullPath()
+		if fullPath == "" {
+			return false
+		}
+		for _, r := range routes {
+			if r == fullPath {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MethodAndRouteSkipper 按请求方法和gin路由模板匹配，路由使用 JoinRouter 拼接，如 GET/users/:id
+func MethodAndRouteSkipper(routes ...string) SkipperFunc {
+	return func(c *gin.Context) bool {
+		fullPath := c.FullPath()
+		if fullPath == "" {
+			return false
+		}
+		router := JoinRouter(c.Request.Method, fullPath)
+		for _, r := range routes {
+			if r == router {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HeaderSkipper 请求头匹配时跳过，未指定values时只要请求头存在即跳过
+func HeaderSkipper(key string, values ...string) SkipperFunc {
+	key = http.CanonicalHeaderKey(key)
+	return func(c *gin.Context) bool {
+		vals, ok := c.Request.Header[key]
+		if !ok {
+			return false
+		}
+		if len(values) == 0 {
+			return true
+		}
+		for _, v := range vals {
+			for _, want := range values {
+				if v == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// MatchPathGlob 判断路径是否匹配通配符模式
+func MatchPathGlob(pattern, path string) bool {
+	return matchSegments(splitPath(pattern), splitPath(path))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, err := stdpath.Match(pattern[0], path[0]); err != nil || !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// hasPathPrefix 按路径段判断前缀
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 中间件创建span使用的instrumentation名称
+const tracerName = "github.com/zhuzhaoman/pkgutil/pkg/middleware"
+
+// HeaderTraceID 响应头中返回trace id
+const HeaderTraceID = "X-Trace-Id"
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	// TracerProvider 默认使用 otel.GetTracerProvider()，测试时可传入使用内存exporter的provider
+	TracerProvider trace.TracerProvider
+	// Propagator 默认使用 W3C traceparent/tracestate 和 baggage
+	Propagator propagation.TextMapPropagator
+	// TraceIDHeader 返回trace id的响应头，默认 X-Trace-Id，为"-"时不返回
+	TraceIDHeader string
+}
+
+// TracingMiddleware OpenTelemetry链路追踪中间件
+// 从请求头中提取上游链路，以路由模板命名创建服务端span，记录HTTP属性、状态码和panic
+func TracingMiddleware(cfg TracingConfig, skippers ...SkipperFunc) gin.HandlerFunc {
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.Propagator == nil {
+		cfg.Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	if cfg.TraceIDHeader == "" {
+		cfg.TraceIDHeader = HeaderTraceID
+	}
+	tracer := cfg.TracerProvider.Tracer(tracerName)
+
+	return func(c *gin.Context) {
+		if SkipHandler(c, skippers...) {
+			c.Next()
+			return
+		}
+
+		req := c.Request
+		ctx := cfg.Propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		route := c.FullPath()
+		spanName := "HTTP " + req.Method
+		if route != "" {
+			spanName = req.Method + " " + route
+		}
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(requestAttributes(c, route)...),
+		)
+		defer span.End()
+
+		if cfg.TraceIDHeader != "-" && span.SpanContext().HasTraceID() {
+			c.Header(cfg.TraceIDHeader, span.SpanContext().TraceID().String())
+		}
+		c.Request = req.WithContext(ctx)
+
+		defer func() {
+			if r := recover(); r != nil {
+				span.RecordError(fmt.Errorf("panic: %v", r), trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, "panic")
+				span.SetAttributes(semconv.HTTPStatusCode(http.StatusInternalServerError))
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
+
+func requestAttributes(c *gin.Context, route string) []attribute.KeyValue {
+	req := c.Request
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethod(req.Method),
+		semconv.HTTPScheme(scheme),
+		semconv.HTTPTarget(req.URL.RequestURI()),
+		semconv.NetHostName(stripPort(req.Host)),
+		semconv.ClientAddress(c.ClientIP()),
+	}
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+	if ua := req.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if req.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestContentLength(int(req.ContentLength)))
+	}
+	return attrs
+}
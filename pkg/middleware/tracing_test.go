@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracingRouter(t *testing.T) (*gin.Engine, *tracetest.InMemoryExporter) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	r := gin.New()
+	r.Use(TracingMiddleware(TracingConfig{TracerProvider: tp}))
+	r.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, c.Param("id")) })
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	return r, exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracingMiddlewareRecordsServerSpan(t *testing.T) {
+	r, exporter := newTracingRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42?x=1", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /users/:id" {
+		t.Errorf("span name = %q, want %q", span.Name, "GET /users/:id")
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", span.SpanKind)
+	}
+	if v, ok := spanAttr(span, "http.route"); !ok || v.AsString() != "/users/:id" {
+		t.Errorf("http.route = %v, want /users/:id", v.Emit())
+	}
+	if v, ok := spanAttr(span, "http.status_code"); !ok || v.AsInt64() != http.StatusOK {
+		t.Errorf("http.status_code = %v, want 200", v.Emit())
+	}
+	if got := w.Header().Get(HeaderTraceID); got != span.SpanContext.TraceID().String() {
+		t.Errorf("%s = %q, want %q", HeaderTraceID, got, span.SpanContext.TraceID())
+	}
+}
+
+func TestTracingMiddlewareContinuesUpstreamTrace(t *testing.T) {
+	r, exporter := newTracingRouter(t)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	parent := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(req.Context(), propagation.HeaderCarrier(req.Header)))
+	if got := spans[0].SpanContext.TraceID(); got != parent.TraceID() {
+		t.Errorf("trace id = %s, want %s", got, parent.TraceID())
+	}
+	if got := spans[0].Parent.SpanID(); got != parent.SpanID() {
+		t.Errorf("parent span id = %s, want %s", got, parent.SpanID())
+	}
+}
+
+func TestTracingMiddlewareMarksErrors(t *testing.T) {
+	r, exporter := newTracingRouter(t)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not re-raised")
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status.Code != codes.Error {
+			t.Errorf("%s: status = %v, want error", span.Name, span.Status.Code)
+		}
+		if v, _ := spanAttr(span, "http.status_code"); v.AsInt64() != http.StatusInternalServerError {
+			t.Errorf("%s: http.status_code = %v, want 500", span.Name, v.Emit())
+		}
+	}
+	if len(spans[1].Events) == 0 || spans[1].Events[0].Name != "exception" {
+		t.Errorf("panic span has no exception event: %+v", spans[1].Events)
+	}
+}
+
+func TestTracingMiddlewareSkipper(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	r := gin.New()
+	r.Use(TracingMiddleware(TracingConfig{TracerProvider: tp}, PathPrefixSkipper("/health")))
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("got %d spans for skipped path, want 0", n)
+	}
+}
@@ -0,0 +1,248 @@
+package rbac
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"github.com/zhuzhaoman/pkgutil/pkg/middleware"
+)
+
+// routeRule 编译后的路由规则
+type routeRule struct {
+	method  string
+	pattern string
+}
+
+// compiledPolicy 展开继承关系后的策略
+type compiledPolicy struct {
+	// rolePerms 角色 -> 权限集合(含继承)
+	rolePerms map[string]map[string]struct{}
+	// permRules 权限 -> 路由规则
+	permRules map[string][]routeRule
+}
+
+// Enforcer 权限判定，可在HTTP之外使用，策略可热更新
+type Enforcer struct {
+	mu     sync.RWMutex
+	policy *compiledPolicy
+}
+
+// NewEnforcer 根据策略创建权限判定器
+func NewEnforcer(policy *Policy) (*Enforcer, error) {
+	e := &Enforcer{}
+	if err := e.SetPolicy(policy); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEnforcerFromFile 从json/yaml文件创建权限判定器
+func NewEnforcerFromFile(path string) (*Enforcer, error) {
+	policy, err := LoadPolicyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEnforcer(policy)
+}
+
+// SetPolicy 替换策略，校验失败时保持原策略不变
+func (e *Enforcer) SetPolicy(policy *Policy) error {
+	compiled, err := compile(policy)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.policy = compiled
+	e.mu.Unlock()
+	return nil
+}
+
+// HasRole 判断roles中是否包含任意一个需要的角色(含继承)
+func (e *Enforcer) HasRole(roles []string, required ...string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range roles {
+		for _, want := range required {
+			if r == want {
+				return true
+			}
+		}
+	}
+	// 继承关系：拥有 admin 且 admin 继承 editor 时视为拥有 editor
+	for _, r := range roles {
+		perms := e.policy.rolePerms[r]
+		for _, want := range required {
+			if _, ok := perms[roleMarker(want)]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission 判断roles是否拥有全部指定权限
+func (e *Enforcer) HasPermission(roles []string, perms ...string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, p := range perms {
+		if !e.hasPermission(roles, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Enforce 判断roles是否可以访问 method+path，path 可以是实际路径或gin路由模板
+func (e *Enforcer) Enforce(roles []string, method, path string) bool {
+	method = strings.ToUpper(method)
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range roles {
+		for perm := range e.policy.rolePerms[r] {
+			for _, rule := range e.policy.permRules[perm] {
+				if rule.match(method, path) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (e *Enforcer) hasPermission(roles []string, perm string) bool {
+	for _, r := range roles {
+		if _, ok := e.policy.rolePerms[r][perm]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchFile 定时检查策略文件修改时间和大小，任一变化时重新加载（包括从备份恢复的较旧文件），返回停止函数
+func (e *Enforcer) WatchFile(path string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	var (
+		modTime time.Time
+		size    int64
+	)
+	if fi, err := os.Stat(path); err == nil {
+		modTime, size = fi.ModTime(), fi.Size()
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fi, err := os.Stat(path)
+				if err != nil {
+					log.Errorf("stat rbac policy file %s error: %s", path, err)
+					continue
+				}
+				if fi.ModTime().Equal(modTime) && fi.Size() == size {
+					continue
+				}
+				modTime, size = fi.ModTime(), fi.Size()
+				policy, err := LoadPolicyFile(path)
+				if err == nil {
+					err = e.SetPolicy(policy)
+				}
+				if err != nil {
+					log.Errorf("reload rbac policy file %s error: %s", path, err)
+					continue
+				}
+				log.Warnf("rbac policy file %s reloaded", path)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (r routeRule) match(method, path string) bool {
+	if r.method != "*" && r.method != method {
+		return false
+	}
+	return r.pattern == path || middleware.MatchPathGlob(r.pattern, path)
+}
+
+// roleMarker 角色继承关系也记录在权限集合中，使用不会与权限名冲突的前缀
+func roleMarker(role string) string {
+	return "role:" + role + "\x00"
+}
+
+func compile(policy *Policy) (*compiledPolicy, error) {
+	if policy == nil {
+		return nil, fmt.Errorf("rbac: policy is nil")
+	}
+	cp := &compiledPolicy{
+		rolePerms: make(map[string]map[string]struct{}, len(policy.Roles)),
+		permRules: make(map[string][]routeRule, len(policy.Permissions)),
+	}
+	for perm, keys := range policy.Permissions {
+		for _, key := range keys {
+			rule, err := parseRouteKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("rbac: permission %s: %w", perm, err)
+			}
+			cp.permRules[perm] = append(cp.permRules[perm], rule)
+		}
+	}
+	for name := range policy.Roles {
+		perms := make(map[string]struct{})
+		if err := expandRole(policy, name, perms, map[string]bool{}); err != nil {
+			return nil, err
+		}
+		cp.rolePerms[name] = perms
+	}
+	return cp, nil
+}
+
+func expandRole(policy *Policy, name string, perms map[string]struct{}, visiting map[string]bool) error {
+	role, ok := policy.Roles[name]
+	if !ok {
+		return fmt.Errorf("rbac: role %s is not defined", name)
+	}
+	if visiting[name] {
+		return fmt.Errorf("rbac: role %s has circular inheritance", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	for _, p := range role.Permissions {
+		if _, ok := policy.Permissions[p]; !ok {
+			return fmt.Errorf("rbac: permission %s of role %s is not defined", p, name)
+		}
+		perms[p] = struct{}{}
+	}
+	for _, parent := range role.Inherits {
+		perms[roleMarker(parent)] = struct{}{}
+		if err := expandRole(policy, parent, perms, visiting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRouteKey 解析 JoinRouter 格式的路由规则，如 GET/api/users
+func parseRouteKey(key string) (routeRule, error) {
+	idx := strings.Index(key, "/")
+	if idx <= 0 {
+		return routeRule{}, fmt.Errorf("invalid route key %q", key)
+	}
+	return routeRule{method: strings.ToUpper(key[:idx]), pattern: key[idx:]}, nil
+}
+
+// RouteKey 生成路由规则，等同于 middleware.JoinRouter
+func RouteKey(method, path string) string {
+	return middleware.JoinRouter(method, path)
+}
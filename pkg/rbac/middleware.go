@@ -0,0 +1,62 @@
+package rbac
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/middleware"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// RolesFunc 从请求中获取当前用户的角色，默认使用 middleware.GetRoles
+type RolesFunc func(c *gin.Context) []string
+
+// Middleware 基于Enforcer的权限中间件
+type Middleware struct {
+	Enforcer *Enforcer
+	Roles    RolesFunc
+}
+
+// NewMiddleware 创建权限中间件
+func NewMiddleware(e *Enforcer) *Middleware {
+	return &Middleware{Enforcer: e, Roles: middleware.GetRoles}
+}
+
+// RequirePermission 要求拥有全部指定权限，否则返回403
+func (m *Middleware) RequirePermission(perms ...string) gin.HandlerFunc {
+	return m.check(func(roles []string, c *gin.Context) bool {
+		return m.Enforcer.HasPermission(roles, perms...)
+	})
+}
+
+// RequireRole 要求拥有任意一个指定角色(含继承)，否则返回403
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return m.check(func(userRoles []string, c *gin.Context) bool {
+		return m.Enforcer.HasRole(userRoles, roles...)
+	})
+}
+
+// Authorize 按当前请求的方法和路径判断是否有权限访问，路径同时匹配实际路径和gin路由模板
+func (m *Middleware) Authorize(skippers ...middleware.SkipperFunc) gin.HandlerFunc {
+	h := m.check(func(roles []string, c *gin.Context) bool {
+		if m.Enforcer.Enforce(roles, c.Request.Method, c.Request.URL.Path) {
+			return true
+		}
+		fullPath := c.FullPath()
+		return fullPath != "" && m.Enforcer.Enforce(roles, c.Request.Method, fullPath)
+	})
+	return middleware.Skippable(middleware.Or(skippers...), h)
+}
+
+func (m *Middleware) check(allow func(roles []string, c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles := m.Roles(c)
+		if len(roles) == 0 {
+			response.Abort(c, response.ErrUnauthorized)
+			return
+		}
+		if !allow(roles, c) {
+			response.Abort(c, response.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
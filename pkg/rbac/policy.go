@@ -0,0 +1,52 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy 权限策略
+// Roles 定义角色拥有的权限和继承的角色，Permissions 定义权限对应的路由规则，
+// 路由规则使用 middleware.JoinRouter 格式，如 GET/api/users/:id、*/api/admin/**
+type Policy struct {
+	Roles       map[string]Role     `json:"roles" yaml:"roles"`
+	Permissions map[string][]string `json:"permissions" yaml:"permissions"`
+}
+
+// Role 角色
+type Role struct {
+	Inherits    []string `json:"inherits" yaml:"inherits"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// ParsePolicy 解析策略，format 为 json 或 yaml
+func ParsePolicy(data []byte, format string) (*Policy, error) {
+	policy := &Policy{}
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, policy)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, policy)
+	default:
+		return nil, fmt.Errorf("unsupported policy format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse policy error: %w", err)
+	}
+	return policy, nil
+}
+
+// LoadPolicyFile 从文件加载策略，根据扩展名判断格式
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
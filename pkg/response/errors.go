@@ -0,0 +1,109 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 业务错误码
+const (
+	CodeSuccess         = 0
+	CodeBadRequest      = 40000
+	CodeUnauthorized    = 40100
+	CodeForbidden       = 40300
+	CodeNotFound        = 40400
+	CodeConflict        = 40900
+	CodeTooLarge        = 41300
+	CodeUnsupportedType = 41500
+	CodeUnprocessable   = 42200
+	CodeTooManyRequests = 42900
+	CodeInternal        = 50000
+	CodeUnavailable     = 50300
+)
+
+// 预定义错误
+var (
+	ErrBadRequest      = NewError(CodeBadRequest, http.StatusBadRequest, "error.bad_request", "bad request")
+	ErrUnauthorized    = NewError(CodeUnauthorized, http.StatusUnauthorized, "error.unauthorized", "unauthorized")
+	ErrForbidden       = NewError(CodeForbidden, http.StatusForbidden, "error.forbidden", "forbidden")
+	ErrNotFound        = NewError(CodeNotFound, http.StatusNotFound, "error.not_found", "not found")
+	ErrConflict        = NewError(CodeConflict, http.StatusConflict, "error.conflict", "conflict")
+	ErrTooLarge        = NewError(CodeTooLarge, http.StatusRequestEntityTooLarge, "error.too_large", "request entity too large")
+	ErrUnsupportedType = NewError(CodeUnsupportedType, http.StatusUnsupportedMediaType, "error.unsupported_media_type", "unsupported media type")
+	ErrUnprocessable   = NewError(CodeUnprocessable, http.StatusUnprocessableEntity, "error.unprocessable", "unprocessable entity")
+	ErrTooManyRequests = NewError(CodeTooManyRequests, http.StatusTooManyRequests, "error.too_many_requests", "too many requests")
+	ErrInternal        = NewError(CodeInternal, http.StatusInternalServerError, "error.internal", "internal server error")
+	ErrUnavailable     = NewError(CodeUnavailable, http.StatusServiceUnavailable, "error.unavailable", "service unavailable")
+)
+
+// Error 带业务码、HTTP状态码和国际化key的错误
+type Error struct {
+	// Code 业务码
+	Code int
+	// Status HTTP状态码
+	Status int
+	// Key 国际化消息key
+	Key string
+	// Message 默认消息，未找到翻译时使用
+	Message string
+	cause   error
+}
+
+// NewError 创建业务错误
+func NewError(code, status int, key, message string) *Error {
+	return &Error{Code: code, Status: status, Key: key, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%d %s: %s", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 业务码相同即认为是同一错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithCause 返回包装了原始错误的副本
+func (e *Error) WithCause(err error) *Error {
+	ne := *e
+	ne.cause = err
+	return &ne
+}
+
+// WithMessage 返回替换了默认消息的副本，替换后不再翻译
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	ne := *e
+	ne.Key = ""
+	if len(args) > 0 {
+		ne.Message = fmt.Sprintf(format, args...)
+	} else {
+		ne.Message = format
+	}
+	return &ne
+}
+
+// FromError 将任意错误转换为业务错误，错误链中没有 *Error 时视为内部错误
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrTooLarge.WithCause(err)
+	}
+	return ErrInternal.WithCause(err)
+}
@@ -0,0 +1,105 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorKey 上下文中保存业务错误的key，供访问日志使用
+const ErrorKey = "response_error"
+
+// Response 统一响应结构
+type Response struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+	TraceID string      `json:"trace_id,omitempty"`
+}
+
+// PageData 分页数据
+type PageData struct {
+	List     interface{} `json:"list"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+// Translator 根据请求语言翻译消息key，未找到时返回空字符串
+type Translator func(c *gin.Context, key string) string
+
+var translator Translator
+
+// SetTranslator 设置消息翻译函数，需在服务启动时调用
+func SetTranslator(t Translator) {
+	translator = t
+}
+
+// OK 成功响应
+func OK(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    CodeSuccess,
+		Message: "success",
+		Data:    data,
+		TraceID: traceID(c),
+	})
+}
+
+// Page 分页成功响应
+func Page(c *gin.Context, list interface{}, total int64, page, pageSize int) {
+	OK(c, PageData{List: list, Total: total, Page: page, PageSize: pageSize})
+}
+
+// Fail 失败响应，err 链中包含 *Error 时使用其业务码和状态码，否则视为内部错误
+func Fail(c *gin.Context, err error) {
+	e := FromError(err)
+	if e == nil {
+		e = ErrInternal
+	}
+	c.Set(ErrorKey, e)
+	_ = c.Error(err)
+	if e.Status >= http.StatusInternalServerError {
+		log.Errorf("request %s %s failed: %s", c.Request.Method, c.Request.URL.Path, err)
+	}
+	c.JSON(e.Status, Response{
+		Code:    e.Code,
+		Message: message(c, e),
+		Data:    nil,
+		TraceID: traceID(c),
+	})
+}
+
+// Abort 失败响应并终止后续处理
+func Abort(c *gin.Context, err error) {
+	Fail(c, err)
+	c.Abort()
+}
+
+// GetError 获取请求处理中记录的业务错误
+func GetError(c *gin.Context) *Error {
+	if v, ok := c.Get(ErrorKey); ok {
+		if e, ok := v.(*Error); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+func message(c *gin.Context, e *Error) string {
+	if translator != nil && e.Key != "" {
+		if msg := translator(c, e.Key); msg != "" {
+			return msg
+		}
+	}
+	return e.Message
+}
+
+func traceID(c *gin.Context) string {
+	spanCtx := trace.SpanContextFromContext(c.Request.Context())
+	if spanCtx.HasTraceID() {
+		return spanCtx.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrOpen 熔断器打开，拒绝请求
+	ErrOpen = errors.New("circuit breaker is open")
+	// ErrTooManyRequests 半开状态下试探请求数已满
+	ErrTooManyRequests = errors.New("circuit breaker is half-open and too many requests")
+)
+
+// Config 熔断器配置
+type Config struct {
+	Name string
+	// Interval 关闭状态下统计窗口长度，窗口结束后清空计数，默认60秒
+	Interval time.Duration
+	// MinRequests 窗口内请求数达到该值才计算失败率，默认20
+	MinRequests int
+	// FailureRate 失败率(含慢调用)达到该值时打开熔断器，默认0.5
+	FailureRate float64
+	// SlowCallDuration 耗时超过该值的调用视为失败，为0时不统计慢调用
+	SlowCallDuration time.Duration
+	// OpenTimeout 打开状态持续时间，之后进入半开状态，默认30秒
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls 半开状态允许的试探请求数，全部成功后关闭熔断器，默认5
+	HalfOpenMaxCalls int
+	// IsFailure 判断错误是否计为失败，默认 err != nil 且不是 context.Canceled
+	IsFailure func(err error) bool
+	// OnStateChange 状态变化回调，默认记录日志，回调在锁内执行，不能再调用熔断器的方法
+	OnStateChange func(name string, from, to State)
+	// Now 当前时间，默认 time.Now，测试时可替换
+	Now func() time.Time
+}
+
+// Counts 统计计数
+type Counts struct {
+	Requests  int
+	Successes int
+	Failures  int
+	// SlowCalls 慢调用数，已计入Failures
+	SlowCalls int
+}
+
+// Breaker 熔断器
+type Breaker struct {
+	cfg Config
+
+	mu       sync.Mutex
+	state    State
+	counts   Counts
+	expireAt time.Time
+	// halfOpenInFlight 半开状态已放行的试探请求数
+	halfOpenInFlight int
+	generation       uint64
+}
+
+// New 创建熔断器
+func New(cfg Config) *Breaker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 5
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	if cfg.OnStateChange == nil {
+		cfg.OnStateChange = func(name string, from, to State) {
+			log.Warnf("circuit breaker %s state changed from %s to %s", name, from, to)
+		}
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	b := &Breaker{cfg: cfg}
+	b.toState(StateClosed, cfg.Now())
+	return b
+}
+
+// Name 熔断器名称
+func (b *Breaker) Name() string {
+	return b.cfg.Name
+}
+
+// State 当前状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, _ := b.currentState(b.cfg.Now())
+	return state
+}
+
+// Counts 当前统计窗口的计数
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.currentState(b.cfg.Now())
+	return b.counts
+}
+
+// Do 在熔断器保护下执行fn，熔断器打开时直接返回 ErrOpen
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			done(errors.New("panic"))
+			panic(r)
+		}
+	}()
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// Allow 判断是否放行请求，放行时返回的done必须在请求结束后调用且只调用一次
+func (b *Breaker) Allow() (done func(err error), err error) {
+	now := b.cfg.Now()
+	b.mu.Lock()
+	state, generation := b.currentState(now)
+	switch state {
+	case StateOpen:
+		b.mu.Unlock()
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenInFlight >= b.cfg.HalfOpenMaxCalls {
+			b.mu.Unlock()
+			return nil, ErrTooManyRequests
+		}
+		b.halfOpenInFlight++
+	}
+	b.counts.Requests++
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			b.afterRequest(generation, b.cfg.IsFailure(err), b.cfg.Now().Sub(now))
+		})
+	}, nil
+}
+
+func (b *Breaker) afterRequest(generation uint64, failed bool, elapsed time.Duration) {
+	now := b.cfg.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, current := b.currentState(now)
+	if generation != current {
+		return
+	}
+	slow := b.cfg.SlowCallDuration > 0 && elapsed > b.cfg.SlowCallDuration
+	if failed || slow {
+		b.counts.Failures++
+		if slow {
+			b.counts.SlowCalls++
+		}
+		b.onFailure(state, now)
+		return
+	}
+	b.counts.Successes++
+	if state == StateHalfOpen && b.counts.Successes >= b.cfg.HalfOpenMaxCalls {
+		b.toState(StateClosed, now)
+	}
+}
+
+func (b *Breaker) onFailure(state State, now time.Time) {
+	switch state {
+	case StateClosed:
+		if b.counts.Requests >= b.cfg.MinRequests &&
+			float64(b.counts.Failures)/float64(b.counts.Requests) >= b.cfg.FailureRate {
+			b.toState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.toState(StateOpen, now)
+	}
+}
+
+// currentState 返回当前状态和代数，处理窗口过期和打开超时
+func (b *Breaker) currentState(now time.Time) (State, uint64) {
+	switch b.state {
+	case StateClosed:
+		if now.After(b.expireAt) {
+			b.newGeneration(now)
+		}
+	case StateOpen:
+		if now.After(b.expireAt) {
+			b.toState(StateHalfOpen, now)
+		}
+	}
+	return b.state, b.generation
+}
+
+func (b *Breaker) toState(state State, now time.Time) {
+	prev := b.state
+	b.state = state
+	b.newGeneration(now)
+	if prev != state && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, prev, state)
+	}
+}
+
+func (b *Breaker) newGeneration(now time.Time) {
+	b.generation++
+	b.counts = Counts{}
+	b.halfOpenInFlight = 0
+	switch b.state {
+	case StateClosed:
+		b.expireAt = now.Add(b.cfg.Interval)
+	case StateOpen:
+		b.expireAt = now.Add(b.cfg.OpenTimeout)
+	default:
+		b.expireAt = time.Time{}
+	}
+}
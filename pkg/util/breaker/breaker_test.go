@@ -0,0 +1,237 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+var errTest = errors.New("backend error")
+
+func newTestBreaker(t *testing.T) (*Breaker, *fakeClock, *[]string) {
+	t.Helper()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	var changes []string
+	b := New(Config{
+		Name:             "test",
+		Interval:         time.Minute,
+		MinRequests:      4,
+		FailureRate:      0.5,
+		SlowCallDuration: time.Second,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenMaxCalls: 2,
+		Now:              clock.Now,
+		OnStateChange: func(_ string, from, to State) {
+			changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+		},
+	})
+	return b, clock, &changes
+}
+
+// call 放行一个请求并立即以 err 结束
+func call(t *testing.T, b *Breaker, err error) {
+	t.Helper()
+	done, aerr := b.Allow()
+	if aerr != nil {
+		t.Fatalf("Allow: %v", aerr)
+	}
+	done(err)
+}
+
+// trip 使熔断器从关闭状态打开
+func trip(t *testing.T, b *Breaker) {
+	t.Helper()
+	for i := 0; i < 4; i++ {
+		call(t, b, errTest)
+	}
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state after failures = %s, want open", s)
+	}
+}
+
+func TestBreakerClosedToOpen(t *testing.T) {
+	b, _, changes := newTestBreaker(t)
+
+	// 请求数未达到 MinRequests 时不计算失败率
+	call(t, b, nil)
+	call(t, b, errTest)
+	call(t, b, errTest)
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("state before MinRequests = %s, want closed", s)
+	}
+	if c := b.Counts(); c != (Counts{Requests: 3, Successes: 1, Failures: 2}) {
+		t.Errorf("counts = %+v", c)
+	}
+	call(t, b, errTest)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state = %s, want open", s)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow when open: %v, want ErrOpen", err)
+	}
+	if fmt.Sprint(*changes) != "[closed->open]" {
+		t.Errorf("state changes = %v", *changes)
+	}
+}
+
+func TestBreakerFailureRateBelowThreshold(t *testing.T) {
+	b, clock, _ := newTestBreaker(t)
+	for _, err := range []error{nil, nil, errTest, nil, context.Canceled} {
+		call(t, b, err)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("state = %s, want closed", s)
+	}
+	// 统计窗口结束后计数清零
+	clock.Add(time.Minute + time.Millisecond)
+	if c := b.Counts(); c != (Counts{}) {
+		t.Errorf("counts after interval = %+v, want zero", c)
+	}
+}
+
+func TestBreakerSlowCallsCountAsFailures(t *testing.T) {
+	b, clock, _ := newTestBreaker(t)
+	for i := 0; i < 4; i++ {
+		done, err := b.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clock.Add(2 * time.Second)
+		done(nil)
+	}
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state after slow calls = %s, want open", s)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b, clock, changes := newTestBreaker(t)
+	trip(t, b)
+
+	clock.Add(10 * time.Second)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state at OpenTimeout = %s, want open", s)
+	}
+	clock.Add(time.Millisecond)
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("state after OpenTimeout = %s, want half-open", s)
+	}
+
+	// 半开状态最多放行 HalfOpenMaxCalls 个试探请求
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("third probe: %v, want ErrTooManyRequests", err)
+	}
+	done1(nil)
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("state after one success = %s, want half-open", s)
+	}
+	done2(nil)
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("state after all probes succeeded = %s, want closed", s)
+	}
+	if fmt.Sprint(*changes) != "[closed->open open->half-open half-open->closed]" {
+		t.Errorf("state changes = %v", *changes)
+	}
+}
+
+func TestBreakerHalfOpenFailureReopens(t *testing.T) {
+	b, clock, _ := newTestBreaker(t)
+	trip(t, b)
+	clock.Add(11 * time.Second)
+
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done1(errTest)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state after probe failure = %s, want open", s)
+	}
+	// 重新打开后，上一轮试探请求的结果不再计数
+	done2(nil)
+	if c := b.Counts(); c != (Counts{}) {
+		t.Errorf("counts after stale probe = %+v, want zero", c)
+	}
+	// 重新计算打开时长
+	clock.Add(10 * time.Second)
+	if s := b.State(); s != StateOpen {
+		t.Errorf("state = %s, want open until the new timeout", s)
+	}
+}
+
+func TestBreakerIgnoresStaleGeneration(t *testing.T) {
+	b, clock, _ := newTestBreaker(t)
+	stale, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未结束的请求也计入请求数，3次失败即达到 MinRequests
+	for i := 0; i < 3; i++ {
+		call(t, b, errTest)
+	}
+	clock.Add(11 * time.Second)
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", s)
+	}
+
+	// 关闭状态时放行的请求在半开状态结束，不能计为试探成功
+	stale(nil)
+	stale(nil)
+	if c := b.Counts(); c != (Counts{}) {
+		t.Errorf("counts after stale result = %+v, want zero", c)
+	}
+	if s := b.State(); s != StateHalfOpen {
+		t.Errorf("state after stale result = %s, want half-open", s)
+	}
+}
+
+func TestBreakerDo(t *testing.T) {
+	b, _, _ := newTestBreaker(t)
+	if err := b.Do(context.Background(), func(context.Context) error { return errTest }); !errors.Is(err, errTest) {
+		t.Errorf("Do = %v, want fn error", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not re-raised")
+			}
+		}()
+		_ = b.Do(context.Background(), func(context.Context) error { panic("boom") })
+	}()
+	if c := b.Counts(); c.Requests != 2 || c.Failures != 2 {
+		t.Errorf("counts = %+v, want 2 failed requests", c)
+	}
+	for i := 0; i < 2; i++ {
+		_ = b.Do(context.Background(), func(context.Context) error { return errTest })
+	}
+	called := false
+	if err := b.Do(context.Background(), func(context.Context) error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Errorf("Do when open = %v, called %v", err, called)
+	}
+}
@@ -0,0 +1,134 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Claims 自定义claims需要嵌入 RegisteredClaims
+//
+//	type UserClaims struct {
+//		jwt.RegisteredClaims
+//		UserID int64 `json:"uid"`
+//	}
+type Claims interface {
+	GetRegisteredClaims() *RegisteredClaims
+}
+
+// RegisteredClaims RFC 7519 4.1 注册的claims
+type RegisteredClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  Audience     `json:"aud,omitempty"`
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+}
+
+// GetRegisteredClaims 实现 Claims 接口
+func (c *RegisteredClaims) GetRegisteredClaims() *RegisteredClaims {
+	return c
+}
+
+// validate 校验时间和签发者、受众，leeway 为允许的时钟偏差
+func (c *RegisteredClaims) validate(now time.Time, leeway time.Duration, issuer string, audience []string) error {
+	if c.ExpiresAt != nil && !now.Before(c.ExpiresAt.Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(leeway).Before(c.NotBefore.Time) {
+		return ErrTokenNotValidYet
+	}
+	if c.IssuedAt != nil && now.Add(leeway).Before(c.IssuedAt.Time) {
+		return ErrTokenUsedBeforeIssued
+	}
+	if issuer != "" && c.Issuer != issuer {
+		return ErrTokenInvalidIssuer
+	}
+	if len(audience) > 0 && !c.Audience.containsAny(audience) {
+		return ErrTokenInvalidAudience
+	}
+	return nil
+}
+
+// NumericDate JSON中以秒为单位的时间戳
+type NumericDate struct {
+	time.Time
+}
+
+// NewNumericDate 创建时间戳，精度截断到秒
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{t.Truncate(time.Second)}
+}
+
+// MarshalJSON 序列化为秒级时间戳
+func (d NumericDate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(d.Unix(), 10)), nil
+}
+
+// UnmarshalJSON 解析秒级时间戳，兼容小数
+func (d *NumericDate) UnmarshalJSON(b []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("%w: invalid numeric date", ErrTokenMalformed)
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%w: invalid numeric date", ErrTokenMalformed)
+	}
+	sec, frac := math.Modf(f)
+	d.Time = time.Unix(int64(sec), int64(frac*1e9))
+	return nil
+}
+
+// Audience aud claim，可以是单个字符串或字符串数组
+type Audience []string
+
+// MarshalJSON 只有一个受众时序列化为字符串
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON 兼容字符串和字符串数组
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case nil:
+		*a = nil
+	case string:
+		*a = Audience{val}
+	case []interface{}:
+		aud := make(Audience, 0, len(val))
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%w: invalid audience", ErrTokenMalformed)
+			}
+			aud = append(aud, s)
+		}
+		*a = aud
+	default:
+		return fmt.Errorf("%w: invalid audience", ErrTokenMalformed)
+	}
+	return nil
+}
+
+func (a Audience) containsAny(want []string) bool {
+	for _, v := range a {
+		for _, w := range want {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
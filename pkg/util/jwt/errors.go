@@ -0,0 +1,19 @@
+package jwt
+
+import "errors"
+
+// 令牌校验错误，可使用 errors.Is 判断
+var (
+	ErrTokenMalformed        = errors.New("jwt: token is malformed")
+	ErrTokenUnverifiable     = errors.New("jwt: token is unverifiable")
+	ErrTokenSignatureInvalid = errors.New("jwt: token signature is invalid")
+	ErrTokenExpired          = errors.New("jwt: token is expired")
+	ErrTokenNotValidYet      = errors.New("jwt: token is not valid yet")
+	ErrTokenUsedBeforeIssued = errors.New("jwt: token used before issued")
+	ErrTokenInvalidIssuer    = errors.New("jwt: token has invalid issuer")
+	ErrTokenInvalidAudience  = errors.New("jwt: token has invalid audience")
+	ErrInvalidKey            = errors.New("jwt: key is invalid")
+	ErrTokenRevoked          = errors.New("jwt: token has been revoked")
+	ErrTokenInvalidType      = errors.New("jwt: token has invalid type")
+	ErrRefreshTokenReused    = errors.New("jwt: refresh token has been reused")
+)
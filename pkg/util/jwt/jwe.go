@@ -0,0 +1,335 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+)
+
+// JWE密钥管理和内容加密算法
+const (
+	AlgRSAOAEP    = "RSA-OAEP"
+	AlgRSAOAEP256 = "RSA-OAEP-256"
+	AlgA256KW     = "A256KW"
+	EncA256GCM    = "A256GCM"
+)
+
+// JWE错误，可使用 errors.Is 判断
+var (
+	ErrJWEMalformed   = errors.New("jwt: jwe is malformed")
+	ErrJWEUnsupported = errors.New("jwt: jwe algorithm is not supported")
+	ErrJWEDecryption  = errors.New("jwt: jwe decryption failed")
+)
+
+// EncryptionKey JWE加密密钥，RSA-OAEP 加密用公钥、解密用私钥，A256KW 两者都是32字节对称密钥
+type EncryptionKey struct {
+	// ID 写入JWE头的kid
+	ID        string
+	Algorithm string
+	// Private 解密密钥：*rsa.PrivateKey 或 []byte，只用于加密时为nil
+	Private interface{}
+	// Public 加密密钥：*rsa.PublicKey 或 []byte
+	Public interface{}
+}
+
+// NewRSAOAEPKey 根据RSA私钥创建加解密密钥，alg 为 RSA-OAEP 或 RSA-OAEP-256，为空时取 RSA-OAEP-256
+func NewRSAOAEPKey(kid, alg string, priv *rsa.PrivateKey) (*EncryptionKey, error) {
+	if priv == nil {
+		return nil, ErrInvalidKey
+	}
+	key, err := NewRSAOAEPPublicKey(kid, alg, &priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key.Private = priv
+	return key, nil
+}
+
+// NewRSAOAEPPublicKey 根据RSA公钥创建只能加密的密钥
+func NewRSAOAEPPublicKey(kid, alg string, pub *rsa.PublicKey) (*EncryptionKey, error) {
+	if alg == "" {
+		alg = AlgRSAOAEP256
+	}
+	if alg != AlgRSAOAEP && alg != AlgRSAOAEP256 {
+		return nil, fmt.Errorf("%w: %s is not a RSA-OAEP algorithm", ErrJWEUnsupported, alg)
+	}
+	if pub == nil || pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%w: RSA key must be at least 2048 bits", ErrInvalidKey)
+	}
+	return &EncryptionKey{ID: kid, Algorithm: alg, Public: pub}, nil
+}
+
+// NewRSAOAEPKeyFromPEM 使用 security.ParsePKCS1Or8PrivKey 解析RSA私钥
+func NewRSAOAEPKeyFromPEM(kid, alg string, privPEM []byte) (*EncryptionKey, error) {
+	priv, err := security.ParsePKCS1Or8PrivKey(privPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return NewRSAOAEPKey(kid, alg, priv)
+}
+
+// NewRSAOAEPPublicKeyFromPEM 使用 security.ParsePublicKey 解析RSA公钥
+func NewRSAOAEPPublicKeyFromPEM(kid, alg string, pubPEM []byte) (*EncryptionKey, error) {
+	pub, err := security.ParsePublicKey(pubPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return NewRSAOAEPPublicKey(kid, alg, pub)
+}
+
+// NewA256KWKey 创建AES密钥包装密钥，secret 必须为32字节
+func NewA256KWKey(kid string, secret []byte) (*EncryptionKey, error) {
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("%w: A256KW key must be 32 bytes", ErrInvalidKey)
+	}
+	return &EncryptionKey{ID: kid, Algorithm: AlgA256KW, Private: secret, Public: secret}, nil
+}
+
+// jweHeader JWE受保护头
+type jweHeader struct {
+	Alg  string   `json:"alg"`
+	Enc  string   `json:"enc"`
+	Kid  string   `json:"kid,omitempty"`
+	Cty  string   `json:"cty,omitempty"`
+	Typ  string   `json:"typ,omitempty"`
+	Zip  string   `json:"zip,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+// Encrypt 使用 A256GCM 加密内容为JWE紧凑格式，cty 为内容类型，嵌套JWT时为 "JWT"
+func Encrypt(plaintext []byte, key *EncryptionKey, cty string) (string, error) {
+	if key == nil || key.Public == nil {
+		return "", ErrInvalidKey
+	}
+	cek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return "", err
+	}
+	encryptedKey, err := wrapKey(key, cek)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(jweHeader{Alg: key.Algorithm, Enc: EncA256GCM, Kid: key.ID, Cty: cty})
+	if err != nil {
+		return "", err
+	}
+	protected := b64(header)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ct, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return strings.Join([]string{protected, b64(encryptedKey), b64(iv), b64(ct), b64(tag)}, "."), nil
+}
+
+// Decrypt 解密JWE紧凑格式，按头中的kid和alg选择密钥，返回明文和内容类型
+func Decrypt(token string, keys ...*EncryptionKey) (plaintext []byte, cty string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, "", ErrJWEMalformed
+	}
+	var raw [5][]byte
+	for i, p := range parts {
+		if raw[i], err = b64Decode(p); err != nil {
+			return nil, "", ErrJWEMalformed
+		}
+	}
+	var header jweHeader
+	if err := json.Unmarshal(raw[0], &header); err != nil {
+		return nil, "", ErrJWEMalformed
+	}
+	if header.Enc != EncA256GCM || header.Zip != "" || len(header.Crit) > 0 {
+		return nil, "", ErrJWEUnsupported
+	}
+	key := findEncryptionKey(keys, header.Kid, header.Alg)
+	if key == nil {
+		return nil, "", fmt.Errorf("%w: no key found to decrypt token", ErrJWEDecryption)
+	}
+
+	cek, err := unwrapKey(key, raw[1])
+	if err != nil || len(cek) != 32 {
+		// 解包失败时使用随机密钥继续，避免通过错误差异探测密钥
+		cek = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return nil, "", err
+		}
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(raw[2]) != gcm.NonceSize() || len(raw[4]) != gcm.Overhead() {
+		return nil, "", ErrJWEMalformed
+	}
+	sealed := append(append([]byte{}, raw[3]...), raw[4]...)
+	plaintext, err = gcm.Open(nil, raw[2], sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, "", ErrJWEDecryption
+	}
+	return plaintext, header.Cty, nil
+}
+
+// SignEncrypted 签名后再加密，生成嵌套JWT
+func (m *Manager) SignEncrypted(claims Claims, key *EncryptionKey) (string, error) {
+	token, err := m.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt([]byte(token), key, "JWT")
+}
+
+// ParseEncrypted 解密嵌套JWT并验签解析到claims
+func (m *Manager) ParseEncrypted(token string, claims Claims, keys ...*EncryptionKey) error {
+	plaintext, cty, err := Decrypt(token, keys...)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(cty, "JWT") {
+		return fmt.Errorf("%w: jwe content is not a JWT", ErrTokenInvalidType)
+	}
+	return m.Parse(string(plaintext), claims)
+}
+
+func findEncryptionKey(keys []*EncryptionKey, kid, alg string) *EncryptionKey {
+	for _, k := range keys {
+		if k == nil || k.Private == nil || k.Algorithm != alg {
+			continue
+		}
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		return k
+	}
+	return nil
+}
+
+func wrapKey(key *EncryptionKey, cek []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case AlgRSAOAEP, AlgRSAOAEP256:
+		pub, ok := key.Public.(*rsa.PublicKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return rsa.EncryptOAEP(oaepHash(key.Algorithm), rand.Reader, pub, cek, nil)
+	case AlgA256KW:
+		kek, ok := key.Public.([]byte)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return aesKeyWrap(kek, cek)
+	}
+	return nil, ErrJWEUnsupported
+}
+
+func unwrapKey(key *EncryptionKey, encryptedKey []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case AlgRSAOAEP, AlgRSAOAEP256:
+		priv, ok := key.Private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return rsa.DecryptOAEP(oaepHash(key.Algorithm), nil, priv, encryptedKey, nil)
+	case AlgA256KW:
+		kek, ok := key.Private.([]byte)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return aesKeyUnwrap(kek, encryptedKey)
+	}
+	return nil, ErrJWEUnsupported
+}
+
+// oaepHash RFC 7518 4.3，RSA-OAEP 使用SHA-1，RSA-OAEP-256 使用SHA-256
+func oaepHash(alg string) hash.Hash {
+	if alg == AlgRSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// kwIV RFC 3394 默认初始值
+var kwIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// aesKeyWrap RFC 3394 AES密钥包装
+func aesKeyWrap(kek, cek []byte) ([]byte, error) {
+	if len(cek)%8 != 0 || len(cek) < 16 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(cek) / 8
+	out := make([]byte, 8+len(cek))
+	copy(out, kwIV)
+	copy(out[8:], cek)
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, out[:8])
+			copy(buf[8:], out[i*8:i*8+8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+// aesKeyUnwrap RFC 3394 AES密钥解包
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, ErrJWEDecryption
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(buf[8:], out[i*8:i*8+8])
+			block.Decrypt(buf, buf)
+			copy(out[:8], buf[:8])
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], kwIV) != 1 {
+		return nil, ErrJWEDecryption
+	}
+	return out[8:], nil
+}
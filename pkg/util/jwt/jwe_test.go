@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testKEK = []byte("0123456789abcdef0123456789abcdef")
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// tamperJWE 翻转JWE第 part 段解码后的第一个字节
+func tamperJWE(t *testing.T, token string, part int) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	raw, err := b64Decode(parts[part])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] ^= 1
+	parts[part] = b64(raw)
+	return strings.Join(parts, ".")
+}
+
+// replaceJWEHeader 替换JWE受保护头，其余部分不变
+func replaceJWEHeader(t *testing.T, token string, edit func(h *jweHeader)) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	raw, _ := b64Decode(parts[0])
+	var h jweHeader
+	if err := json.Unmarshal(raw, &h); err != nil {
+		t.Fatal(err)
+	}
+	edit(&h)
+	raw, _ = json.Marshal(h)
+	parts[0] = b64(raw)
+	return strings.Join(parts, ".")
+}
+
+func TestAESKeyWrapRFC3394(t *testing.T) {
+	// RFC 3394 4.6 使用256位KEK包装256位密钥
+	kek := mustHex(t, "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	cek := mustHex(t, "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	want := mustHex(t, "28C9F404C4B810F4 CBCCB35CFB87F826 3F5786E2D80ED326 CBC7F0E71A99F43B FB988B9B7A02DD21")
+
+	wrapped, err := aesKeyWrap(kek, cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(wrapped, want) {
+		t.Fatalf("wrap = %X, want %X", wrapped, want)
+	}
+	got, err := aesKeyUnwrap(kek, wrapped)
+	if err != nil || !bytes.Equal(got, cek) {
+		t.Fatalf("unwrap = %X, %v", got, err)
+	}
+
+	wrapped[len(wrapped)-1] ^= 1
+	if _, err := aesKeyUnwrap(kek, wrapped); !errors.Is(err, ErrJWEDecryption) {
+		t.Errorf("unwrap tampered key: %v, want ErrJWEDecryption", err)
+	}
+	if _, err := aesKeyUnwrap(kek, want[:16]); !errors.Is(err, ErrJWEDecryption) {
+		t.Errorf("unwrap short key: %v, want ErrJWEDecryption", err)
+	}
+}
+
+func testEncryptionKeys(t *testing.T) map[string]*EncryptionKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oaep, err := NewRSAOAEPKey("rsa1", AlgRSAOAEP, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oaep256, err := NewRSAOAEPKey("rsa2", AlgRSAOAEP256, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kw, err := NewA256KWKey("kw", testKEK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]*EncryptionKey{AlgRSAOAEP: oaep, AlgRSAOAEP256: oaep256, AlgA256KW: kw}
+}
+
+func TestJWERoundTrip(t *testing.T) {
+	for alg, key := range testEncryptionKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			token, err := Encrypt([]byte("hello jwe"), key, "text/plain")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := strings.Count(token, "."); n != 4 {
+				t.Fatalf("token has %d dots, want 4", n)
+			}
+			pt, cty, err := Decrypt(token, key)
+			if err != nil || string(pt) != "hello jwe" || cty != "text/plain" {
+				t.Fatalf("Decrypt = %q, %q, %v", pt, cty, err)
+			}
+			// 只有公钥的密钥可以加密但不能解密
+			pub := *key
+			pub.Private = nil
+			token, err = Encrypt([]byte("x"), &pub, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := Decrypt(token, &pub); !errors.Is(err, ErrJWEDecryption) {
+				t.Errorf("decrypt with public key only: %v, want ErrJWEDecryption", err)
+			}
+		})
+	}
+}
+
+func TestJWERejects(t *testing.T) {
+	key, err := NewA256KWKey("kw", testKEK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Encrypt([]byte("hello jwe"), key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewA256KWKey("kw", []byte("fedcba9876543210fedcba9876543210"))
+
+	tests := []struct {
+		name  string
+		token string
+		key   *EncryptionKey
+		want  error
+	}{
+		{"tampered encrypted key", tamperJWE(t, token, 1), key, ErrJWEDecryption},
+		{"tampered iv", tamperJWE(t, token, 2), key, ErrJWEDecryption},
+		{"tampered ciphertext", tamperJWE(t, token, 3), key, ErrJWEDecryption},
+		{"tampered tag", tamperJWE(t, token, 4), key, ErrJWEDecryption},
+		{"tampered protected header", replaceJWEHeader(t, token, func(h *jweHeader) { h.Typ = "JWT" }), key, ErrJWEDecryption},
+		{"wrong key", token, other, ErrJWEDecryption},
+		{"kid mismatch", replaceJWEHeader(t, token, func(h *jweHeader) { h.Kid = "other" }), key, ErrJWEDecryption},
+		{"unsupported alg", replaceJWEHeader(t, token, func(h *jweHeader) { h.Alg = "dir" }), key, ErrJWEDecryption},
+		{"unsupported enc", replaceJWEHeader(t, token, func(h *jweHeader) { h.Enc = "A128CBC-HS256" }), key, ErrJWEUnsupported},
+		{"zip", replaceJWEHeader(t, token, func(h *jweHeader) { h.Zip = "DEF" }), key, ErrJWEUnsupported},
+		{"crit", replaceJWEHeader(t, token, func(h *jweHeader) { h.Crit = []string{"exp"} }), key, ErrJWEUnsupported},
+		{"four parts", token[:strings.LastIndex(token, ".")], key, ErrJWEMalformed},
+		{"bad base64", token + "!", key, ErrJWEMalformed},
+		{"short tag", token[:strings.LastIndex(token, ".")+3], key, ErrJWEMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, _, err := Decrypt(tt.token, tt.key)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decrypt = %q, %v, want %v", pt, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionKeyValidation(t *testing.T) {
+	if _, err := NewA256KWKey("kw", testKEK[:16]); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("16 byte A256KW key: %v, want ErrInvalidKey", err)
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRSAOAEPKey("rsa", AlgRSAOAEP256, priv); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("1024 bit RSA key: %v, want ErrInvalidKey", err)
+	}
+	if _, err := NewRSAOAEPKey("rsa", "RSA1_5", priv); !errors.Is(err, ErrJWEUnsupported) {
+		t.Errorf("RSA1_5: %v, want ErrJWEUnsupported", err)
+	}
+	if _, err := Encrypt([]byte("x"), &EncryptionKey{Algorithm: "dir", Public: testKEK}, ""); !errors.Is(err, ErrJWEUnsupported) {
+		t.Errorf("encrypt with dir: %v, want ErrJWEUnsupported", err)
+	}
+}
+
+func TestManagerSignEncrypted(t *testing.T) {
+	m, err := NewManager(ManagerConfig{Key: []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := testEncryptionKeys(t)[AlgRSAOAEP256]
+
+	token, err := m.SignEncrypted(&RegisteredClaims{Subject: "alice"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rc RegisteredClaims
+	if err := m.ParseEncrypted(token, &rc, key); err != nil || rc.Subject != "alice" {
+		t.Fatalf("ParseEncrypted = %+v, %v", rc, err)
+	}
+	// 内层JWT仍然需要验签
+	other, _ := NewManager(ManagerConfig{Key: []byte("fedcba9876543210fedcba9876543210")})
+	if err := other.ParseEncrypted(token, &RegisteredClaims{}, key); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("parse with wrong signing key: %v, want ErrTokenSignatureInvalid", err)
+	}
+	// 内容不是JWT时拒绝
+	plain, _ := Encrypt([]byte("not a jwt"), key, "text/plain")
+	if err := m.ParseEncrypted(plain, &RegisteredClaims{}, key); !errors.Is(err, ErrTokenInvalidType) {
+		t.Errorf("parse non-JWT content: %v, want ErrTokenInvalidType", err)
+	}
+}
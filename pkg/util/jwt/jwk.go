@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK RFC 7517 公钥，只包含验签需要的字段
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC/OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet RFC 7517 密钥集合
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK 导出公钥，HMAC密钥不能公开，返回false
+func (k *Key) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = b64(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// Key 转换为验签密钥
+func (j JWK) Key() (*Key, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := b64Decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64Decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA exponent", ErrInvalidKey)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return NewVerifyKey(j.Kid, j.Alg, pub)
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrInvalidKey, j.Crv)
+		}
+		x, err := b64Decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64Decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve", ErrInvalidKey)
+		}
+		return NewVerifyKey(j.Kid, j.Alg, pub)
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrInvalidKey, j.Crv)
+		}
+		x, err := b64Decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key size", ErrInvalidKey)
+		}
+		return NewVerifyKey(j.Kid, j.Alg, ed25519.PublicKey(x))
+	}
+	return nil, fmt.Errorf("%w: unsupported key type %s", ErrInvalidKey, j.Kty)
+}
+
+// Lookup 在集合中按kid和alg查找密钥，跳过无法解析和非签名用途的密钥
+func (s JWKSet) Lookup(kid, alg string) (*Key, error) {
+	for _, j := range s.Keys {
+		if j.Kid != kid || (j.Use != "" && j.Use != "sig") {
+			continue
+		}
+		key, err := j.Key()
+		if err != nil || key.Algorithm != alg {
+			continue
+		}
+		return key, nil
+	}
+	return nil, errNoVerifyKey
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func b64Decode(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return b, nil
+}
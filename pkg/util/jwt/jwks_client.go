@@ -0,0 +1,138 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKSClientConfig 远程JWKS客户端配置
+type JWKSClientConfig struct {
+	// URL JWKS地址，如 https://auth.example.com/.well-known/jwks.json
+	URL string
+	// HTTPClient 默认超时10秒
+	HTTPClient *http.Client
+	// RefreshInterval 缓存有效期，默认10分钟
+	RefreshInterval time.Duration
+	// MinRefreshInterval 遇到未知kid时强制刷新的最小间隔，防止被恶意kid刷爆，默认1分钟
+	MinRefreshInterval time.Duration
+}
+
+// JWKSClient 拉取并缓存远程JWKS，实现 KeyResolver
+type JWKSClient struct {
+	cfg JWKSClientConfig
+	now func() time.Time
+
+	mu        sync.RWMutex
+	set       JWKSet
+	fetchedAt time.Time
+	// attemptedAt 最近一次拉取的时间，无论成功与否，两次拉取至少间隔 MinRefreshInterval
+	attemptedAt time.Time
+	lastErr     error
+	// fetchMu 保证同一时间只有一个刷新请求
+	fetchMu sync.Mutex
+}
+
+// NewJWKSClient 创建JWKS客户端，首次查找时拉取
+func NewJWKSClient(cfg JWKSClientConfig) (*JWKSClient, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("jwt: jwks url is empty")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 10 * time.Minute
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = time.Minute
+	}
+	return &JWKSClient{cfg: cfg, now: time.Now}, nil
+}
+
+// Lookup 实现 KeyResolver，缓存过期或找不到kid时刷新，
+// 距上次拉取（包括失败的拉取）不足 MinRefreshInterval 时只使用缓存
+func (c *JWKSClient) Lookup(kid, alg string) (*Key, error) {
+	set, fetchedAt, attemptedAt, lastErr := c.cached()
+	now := c.now()
+	if now.Sub(fetchedAt) < c.cfg.RefreshInterval {
+		if key, err := set.Lookup(kid, alg); err == nil {
+			return key, nil
+		}
+	}
+	if !attemptedAt.IsZero() && now.Sub(attemptedAt) < c.cfg.MinRefreshInterval {
+		return c.lookupStale(set, kid, alg, lastErr)
+	}
+	if err := c.refresh(context.Background(), attemptedAt); err != nil {
+		// 刷新失败时继续使用旧的缓存
+		return c.lookupStale(set, kid, alg, err)
+	}
+	set, _, _, _ = c.cached()
+	return set.Lookup(kid, alg)
+}
+
+// lookupStale 在可能过期的缓存中查找，找不到时附带最近一次拉取的错误
+func (c *JWKSClient) lookupStale(set JWKSet, kid, alg string, fetchErr error) (*Key, error) {
+	key, err := set.Lookup(kid, alg)
+	if err != nil && fetchErr != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTokenUnverifiable, fetchErr)
+	}
+	return key, err
+}
+
+// Refresh 立即拉取远程JWKS，不受 MinRefreshInterval 限制
+func (c *JWKSClient) Refresh(ctx context.Context) error {
+	_, _, attemptedAt, _ := c.cached()
+	return c.refresh(ctx, attemptedAt)
+}
+
+func (c *JWKSClient) cached() (set JWKSet, fetchedAt, attemptedAt time.Time, lastErr error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.set, c.fetchedAt, c.attemptedAt, c.lastErr
+}
+
+// refresh 拉取JWKS，如果等锁期间其他请求已经拉取过则直接返回其结果
+func (c *JWKSClient) refresh(ctx context.Context, seen time.Time) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if _, _, attemptedAt, lastErr := c.cached(); attemptedAt.After(seen) {
+		return lastErr
+	}
+
+	set, err := c.fetch(ctx)
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attemptedAt, c.lastErr = now, err
+	if err != nil {
+		return err
+	}
+	c.set, c.fetchedAt = set, now
+	return nil
+}
+
+func (c *JWKSClient) fetch(ctx context.Context) (JWKSet, error) {
+	var set JWKSet
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return set, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return set, fmt.Errorf("jwt: fetch jwks error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return set, fmt.Errorf("jwt: fetch jwks status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return set, fmt.Errorf("jwt: decode jwks error: %w", err)
+	}
+	return set, nil
+}
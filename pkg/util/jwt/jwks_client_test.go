@@ -0,0 +1,259 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer 可修改返回内容并统计请求次数的JWKS服务
+type jwksServer struct {
+	*httptest.Server
+	hits int32
+
+	mu     sync.Mutex
+	set    JWKSet
+	status int
+}
+
+func newJWKSServer(t *testing.T, keys ...*Key) *jwksServer {
+	t.Helper()
+	s := &jwksServer{status: http.StatusOK}
+	s.setKeys(t, keys...)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.hits, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(t *testing.T, keys ...*Key) {
+	t.Helper()
+	var set JWKSet
+	for _, k := range keys {
+		jwk, ok := k.JWK()
+		if !ok {
+			t.Fatalf("key %s cannot be exported as JWK", k.ID)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	s.mu.Lock()
+	s.set = set
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) setStatus(status int) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) Hits() int {
+	return int(atomic.LoadInt32(&s.hits))
+}
+
+// fakeClock 测试用时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestES256Key(t *testing.T, kid string) *Key {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewSigningKey(kid, AlgES256, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func newTestJWKSClient(t *testing.T, url string) (*JWKSClient, *fakeClock) {
+	t.Helper()
+	c, err := NewJWKSClient(JWKSClientConfig{
+		URL:                url,
+		RefreshInterval:    10 * time.Minute,
+		MinRefreshInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	c.now = clock.Now
+	return c, clock
+}
+
+func TestJWKSClientCacheHit(t *testing.T) {
+	k1 := newTestES256Key(t, "k1")
+	srv := newJWKSServer(t, k1)
+	c, clock := newTestJWKSClient(t, srv.URL)
+
+	for i := 0; i < 3; i++ {
+		key, err := c.Lookup("k1", AlgES256)
+		if err != nil {
+			t.Fatalf("lookup %d: %v", i, err)
+		}
+		if key.ID != "k1" {
+			t.Fatalf("got kid %q, want k1", key.ID)
+		}
+		clock.Advance(2 * time.Minute)
+	}
+	if srv.Hits() != 1 {
+		t.Errorf("server hits = %d, want 1", srv.Hits())
+	}
+
+	// 超过 RefreshInterval 后重新拉取
+	clock.Advance(10 * time.Minute)
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Hits() != 2 {
+		t.Errorf("server hits after expiry = %d, want 2", srv.Hits())
+	}
+}
+
+func TestJWKSClientRefreshOnUnknownKid(t *testing.T) {
+	k1, k2 := newTestES256Key(t, "k1"), newTestES256Key(t, "k2")
+	srv := newJWKSServer(t, k1)
+	c, clock := newTestJWKSClient(t, srv.URL)
+
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	srv.setKeys(t, k1, k2)
+	clock.Advance(time.Minute)
+
+	key, err := c.Lookup("k2", AlgES256)
+	if err != nil {
+		t.Fatalf("lookup rotated kid: %v", err)
+	}
+	if key.ID != "k2" {
+		t.Errorf("got kid %q, want k2", key.ID)
+	}
+	if srv.Hits() != 2 {
+		t.Errorf("server hits = %d, want 2", srv.Hits())
+	}
+}
+
+func TestJWKSClientRateLimitsUnknownKid(t *testing.T) {
+	srv := newJWKSServer(t, newTestES256Key(t, "k1"))
+	c, clock := newTestJWKSClient(t, srv.URL)
+
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := c.Lookup("unknown", AlgES256); err == nil {
+			t.Fatal("lookup of unknown kid succeeded")
+		}
+		clock.Advance(time.Second)
+	}
+	if srv.Hits() != 1 {
+		t.Errorf("server hits within MinRefreshInterval = %d, want 1", srv.Hits())
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := c.Lookup("unknown", AlgES256); err == nil {
+		t.Fatal("lookup of unknown kid succeeded")
+	}
+	if srv.Hits() != 2 {
+		t.Errorf("server hits after MinRefreshInterval = %d, want 2", srv.Hits())
+	}
+}
+
+func TestJWKSClientStaleFallbackAndBackoff(t *testing.T) {
+	srv := newJWKSServer(t, newTestES256Key(t, "k1"))
+	c, clock := newTestJWKSClient(t, srv.URL)
+
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	srv.setStatus(http.StatusInternalServerError)
+	clock.Advance(11 * time.Minute)
+
+	// 缓存已过期且拉取失败，继续使用旧的缓存
+	for i := 0; i < 5; i++ {
+		key, err := c.Lookup("k1", AlgES256)
+		if err != nil {
+			t.Fatalf("stale lookup %d: %v", i, err)
+		}
+		if key.ID != "k1" {
+			t.Fatalf("got kid %q, want k1", key.ID)
+		}
+		clock.Advance(time.Second)
+	}
+	if srv.Hits() != 2 {
+		t.Errorf("server hits after failed refresh = %d, want 2", srv.Hits())
+	}
+
+	// 失败后同样遵守 MinRefreshInterval，未知kid返回拉取错误
+	_, err := c.Lookup("unknown", AlgES256)
+	if !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("unknown kid error = %v, want ErrTokenUnverifiable", err)
+	}
+	if srv.Hits() != 2 {
+		t.Errorf("server hits = %d, want 2", srv.Hits())
+	}
+
+	srv.setStatus(http.StatusOK)
+	clock.Advance(time.Minute)
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Hits() != 3 {
+		t.Errorf("server hits after recovery = %d, want 3", srv.Hits())
+	}
+	clock.Advance(time.Minute)
+	if _, err := c.Lookup("k1", AlgES256); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Hits() != 3 {
+		t.Errorf("server hits with fresh cache = %d, want 3", srv.Hits())
+	}
+}
+
+func TestJWKSClientNoCacheFetchError(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.setStatus(http.StatusServiceUnavailable)
+	c, _ := newTestJWKSClient(t, srv.URL)
+
+	if _, err := c.Lookup("k1", AlgES256); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("error = %v, want ErrTokenUnverifiable", err)
+	}
+	if _, err := c.Lookup("k1", AlgES256); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("error = %v, want ErrTokenUnverifiable", err)
+	}
+	if srv.Hits() != 1 {
+		t.Errorf("server hits = %d, want 1", srv.Hits())
+	}
+}
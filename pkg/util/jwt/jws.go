@@ -0,0 +1,235 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// keyFunc 根据令牌头返回验签密钥
+type keyFunc func(header map[string]interface{}) (interface{}, error)
+
+// jwsHash 各算法使用的摘要
+var jwsHash = map[string]crypto.Hash{
+	AlgHS256: crypto.SHA256,
+	AlgHS384: crypto.SHA384,
+	AlgHS512: crypto.SHA512,
+	AlgRS256: crypto.SHA256,
+	AlgRS384: crypto.SHA384,
+	AlgRS512: crypto.SHA512,
+	AlgES256: crypto.SHA256,
+	AlgES384: crypto.SHA384,
+	AlgES512: crypto.SHA512,
+	AlgEdDSA: 0,
+}
+
+// signToken 生成JWS紧凑格式令牌，header 为额外的令牌头
+func signToken(alg string, header map[string]interface{}, claims interface{}, key interface{}) (string, error) {
+	if _, ok := jwsHash[alg]; !ok {
+		return "", fmt.Errorf("jwt: unsupported signing algorithm %s", alg)
+	}
+	h := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	for k, v := range header {
+		h[k] = v
+	}
+	h["alg"] = alg
+	hb, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("jwt: encode header error: %w", err)
+	}
+	pb, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt: encode claims error: %w", err)
+	}
+	signingInput := b64(hb) + "." + b64(pb)
+	sig, err := sign(alg, []byte(signingInput), key)
+	if err != nil {
+		return "", fmt.Errorf("jwt: sign token error: %w", err)
+	}
+	return signingInput + "." + b64(sig), nil
+}
+
+// parseToken 解析并验签，只接受 algs 中的算法，algs 为空时接受所有支持的算法，始终拒绝 none
+func parseToken(tokenString string, claims interface{}, algs []string, kf keyFunc) error {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: token contains an invalid number of segments", ErrTokenMalformed)
+	}
+	hb, err := b64Decode(parts[0])
+	if err != nil {
+		return fmt.Errorf("%w: invalid header encoding", ErrTokenMalformed)
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(hb, &header); err != nil {
+		return fmt.Errorf("%w: invalid header", ErrTokenMalformed)
+	}
+	pb, err := b64Decode(parts[1])
+	if err != nil {
+		return fmt.Errorf("%w: invalid claims encoding", ErrTokenMalformed)
+	}
+	sig, err := b64Decode(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature encoding", ErrTokenMalformed)
+	}
+
+	alg, _ := header["alg"].(string)
+	if _, ok := jwsHash[alg]; !ok {
+		return fmt.Errorf("%w: signing algorithm %q is not supported", ErrTokenSignatureInvalid, alg)
+	}
+	if len(algs) > 0 && !containsAlg(algs, alg) {
+		return fmt.Errorf("%w: signing algorithm %s is not allowed", ErrTokenSignatureInvalid, alg)
+	}
+	// 不支持任何扩展头，按 RFC 7515 4.1.11 必须拒绝
+	if _, ok := header["crit"]; ok {
+		return fmt.Errorf("%w: crit header is not supported", ErrTokenUnverifiable)
+	}
+
+	key, err := kf(header)
+	if err != nil {
+		if errors.Is(err, ErrInvalidKey) || errors.Is(err, ErrTokenUnverifiable) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrTokenUnverifiable, err)
+	}
+	signingInput := parts[0] + "." + parts[1]
+	if err := verify(alg, []byte(signingInput), sig, key); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(pb, claims); err != nil {
+		if errors.Is(err, ErrTokenMalformed) {
+			return err
+		}
+		return fmt.Errorf("%w: invalid claims: %s", ErrTokenMalformed, err)
+	}
+	return nil
+}
+
+func sign(alg string, signingInput []byte, key interface{}) ([]byte, error) {
+	hash := jwsHash[alg]
+	switch alg {
+	case AlgHS256, AlgHS384, AlgHS512:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return nil, ErrInvalidKey
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	case AlgRS256, AlgRS384, AlgRS512:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		return rsa.SignPKCS1v15(rand.Reader, priv, hash, digest(hash, signingInput))
+	case AlgES256, AlgES384, AlgES512:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok || ecdsaAlgorithm(priv.Curve) != alg {
+			return nil, ErrInvalidKey
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest(hash, signingInput))
+		if err != nil {
+			return nil, err
+		}
+		// RFC 7518 3.4，r和s按曲线长度定长拼接
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	case AlgEdDSA:
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok || len(priv) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKey
+		}
+		return ed25519.Sign(priv, signingInput), nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported signing algorithm %s", alg)
+}
+
+// verify 验签，密钥类型必须与算法匹配，防止用RSA公钥作为HMAC密钥的算法混淆攻击
+func verify(alg string, signingInput, sig []byte, key interface{}) error {
+	hash := jwsHash[alg]
+	switch alg {
+	case AlgHS256, AlgHS384, AlgHS512:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return fmt.Errorf("%w: %s requires a HMAC key", ErrInvalidKey, alg)
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrTokenSignatureInvalid
+		}
+	case AlgRS256, AlgRS384, AlgRS512:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s requires a RSA public key", ErrInvalidKey, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest(hash, signingInput), sig); err != nil {
+			return ErrTokenSignatureInvalid
+		}
+	case AlgES256, AlgES384, AlgES512:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecdsaAlgorithm(pub.Curve) != alg {
+			return fmt.Errorf("%w: %s requires a matching ECDSA public key", ErrInvalidKey, alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrTokenSignatureInvalid
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest(hash, signingInput), r, s) {
+			return ErrTokenSignatureInvalid
+		}
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: %s requires an Ed25519 public key", ErrInvalidKey, alg)
+		}
+		if !ed25519.Verify(pub, signingInput, sig) {
+			return ErrTokenSignatureInvalid
+		}
+	default:
+		return fmt.Errorf("%w: signing algorithm %s is not supported", ErrTokenSignatureInvalid, alg)
+	}
+	return nil
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// DecodeUnverified 只解码令牌头和claims，不验签，仅用于调试和展示，不能用于鉴权
+func DecodeUnverified(tokenString string) (header, claims map[string]interface{}, err error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("%w: token contains an invalid number of segments", ErrTokenMalformed)
+	}
+	for i, v := range []*map[string]interface{}{&header, &claims} {
+		seg, err := b64Decode(parts[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid segment encoding", ErrTokenMalformed)
+		}
+		dec := json.NewDecoder(bytes.NewReader(seg))
+		dec.UseNumber()
+		if err := dec.Decode(v); err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid segment: %s", ErrTokenMalformed, err)
+		}
+	}
+	return header, claims, nil
+}
@@ -0,0 +1,292 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 7515 附录A 的示例，A.1 同时也是 RFC 7519 3.1 的示例
+const (
+	rfcPayload = "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ"
+
+	rfcA1Token = "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9." + rfcPayload +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcA1Key = "AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"
+
+	rfcA2Token = "eyJhbGciOiJSUzI1NiJ9." + rfcPayload +
+		".cC4hiUPoj9Eetdgtv3hF80EGrhuB__dzERat0XF9g2VtQgr9PJbu3XOiZj5RZmh7AAuHIm4Bh-0Qc_lF5YKt_O8W2Fp5jujGbds9uJdbF9CUAr7t1dnZcAcQjbKBYNX4BAynRFdiuB--f_nZLgrnbyTyWzO75vRK5h6xBArLIARNPvkSjtQBMHlb1L07Qe7K0GarZRmB_eSN9383LcOLn6_dO--xi12jzDwusC-eOkHWEsqtFZESc6BfI7noOPqvhJ1phCnvWh6IeYI2w9QOYEUipUTI8np6LbgGY9Fs98rqVt5AXLIhWkWywlVmtVrBp0igcN_IoypGlUPQGe77Rw"
+	rfcA2N = "ofgWCuLjybRlzo0tZWJjNiuSfb4p4fAkd_wWJcyQoTbji9k0l8W26mPddxHmfHQp-Vaw-4qPCJrcS2mJPMEzP1Pt0Bm4d4QlL-yRT-SFd2lZS-pCgNMsD1W_YpRPEwOWvG6b32690r2jZ47soMZo9wGzjb_7OMg0LOL-bSf63kpaSHSXndS5z5rexMdbBYUsLA9e-KXBdQOS-UTo7WTBEMa2R2CapHg665xsmtdVMTBQY4uDZlxvb3qCo5ZwKh9kG4LT6_I5IhlJH7aGhyxXFvUK-DWNmoudF8NAco9_h9iaGNj8q2ethFkMLs91kzk2PAcDTW9gb54h4FRWyuXpoQ"
+
+	rfcA3Token = "eyJhbGciOiJFUzI1NiJ9." + rfcPayload +
+		".DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+	rfcA3X = "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"
+	rfcA3Y = "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"
+
+	// RFC 8037 A.4，载荷不是JSON，只校验签名
+	rfc8037Token = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc" +
+		".hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+	rfc8037X = "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+)
+
+func staticKey(key interface{}) keyFunc {
+	return func(map[string]interface{}) (interface{}, error) { return key, nil }
+}
+
+func jwkPublicKey(t *testing.T, j JWK) interface{} {
+	t.Helper()
+	k, err := j.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.Public
+}
+
+func checkRFCClaims(t *testing.T, claims map[string]interface{}) {
+	t.Helper()
+	if claims["iss"] != "joe" {
+		t.Errorf("iss = %v, want joe", claims["iss"])
+	}
+	if claims["exp"] != float64(1300819380) {
+		t.Errorf("exp = %v, want 1300819380", claims["exp"])
+	}
+	if claims["http://example.com/is_root"] != true {
+		t.Errorf("is_root = %v, want true", claims["http://example.com/is_root"])
+	}
+}
+
+func TestParseTokenRFCVectors(t *testing.T) {
+	hmacKey, err := b64Decode(rfcA1Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		token string
+		key   interface{}
+	}{
+		{"RFC 7515 A.1 HS256", rfcA1Token, hmacKey},
+		{"RFC 7515 A.2 RS256", rfcA2Token, jwkPublicKey(t, JWK{Kty: "RSA", N: rfcA2N, E: "AQAB"})},
+		{"RFC 7515 A.3 ES256", rfcA3Token, jwkPublicKey(t, JWK{Kty: "EC", Crv: "P-256", X: rfcA3X, Y: rfcA3Y})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]interface{}{}
+			if err := parseToken(tt.token, &claims, nil, staticKey(tt.key)); err != nil {
+				t.Fatalf("parseToken: %v", err)
+			}
+			checkRFCClaims(t, claims)
+		})
+	}
+}
+
+func TestVerifyRFC8037EdDSA(t *testing.T) {
+	parts := strings.Split(rfc8037Token, ".")
+	sig, err := b64Decode(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := jwkPublicKey(t, JWK{Kty: "OKP", Crv: "Ed25519", X: rfc8037X})
+	if err := verify(AlgEdDSA, []byte(parts[0]+"."+parts[1]), sig, pub); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	sig[0] ^= 1
+	if err := verify(AlgEdDSA, []byte(parts[0]+"."+parts[1]), sig, pub); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("tampered signature error = %v, want ErrTokenSignatureInvalid", err)
+	}
+}
+
+func TestRFC7519ExampleIsExpired(t *testing.T) {
+	hmacKey, _ := b64Decode(rfcA1Key)
+	if _, ok := ParseToken(rfcA1Token, string(hmacKey)); ok {
+		t.Fatal("ParseToken accepted the expired RFC 7519 example")
+	}
+	claims := map[string]interface{}{}
+	if err := parseToken(rfcA1Token, &claims, nil, staticKey(hmacKey)); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateMapClaims(claims, time.Unix(1300819379, 0)); err != nil {
+		t.Errorf("before exp: %v", err)
+	}
+	if err := validateMapClaims(claims, time.Unix(1300819380, 0)); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("at exp: %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestSignParseRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		alg       string
+		priv, pub interface{}
+	}{
+		{AlgHS256, secret, secret},
+		{AlgHS384, secret, secret},
+		{AlgHS512, secret, secret},
+		{AlgRS256, rsaKey, &rsaKey.PublicKey},
+		{AlgRS384, rsaKey, &rsaKey.PublicKey},
+		{AlgRS512, rsaKey, &rsaKey.PublicKey},
+		{AlgES256, p256, &p256.PublicKey},
+		{AlgES384, p384, &p384.PublicKey},
+		{AlgES512, p521, &p521.PublicKey},
+		{AlgEdDSA, edPriv, edPub},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			token, err := signToken(tt.alg, map[string]interface{}{"kid": "k1"}, map[string]interface{}{"sub": "u1"}, tt.priv)
+			if err != nil {
+				t.Fatalf("signToken: %v", err)
+			}
+			claims := map[string]interface{}{}
+			if err := parseToken(token, &claims, []string{tt.alg}, staticKey(tt.pub)); err != nil {
+				t.Fatalf("parseToken: %v", err)
+			}
+			if claims["sub"] != "u1" {
+				t.Errorf("sub = %v, want u1", claims["sub"])
+			}
+		})
+	}
+}
+
+// unsignedToken 使用指定令牌头构造签名为空的令牌
+func unsignedToken(header string) string {
+	return b64([]byte(header)) + "." + b64([]byte(`{"sub":"u1"}`)) + "."
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	hsToken, _ := signToken(AlgHS256, nil, map[string]interface{}{"sub": "u1"}, secret)
+	rsToken, _ := signToken(AlgRS256, nil, map[string]interface{}{"sub": "u1"}, rsaKey)
+	esToken, _ := signToken(AlgES256, nil, map[string]interface{}{"sub": "u1"}, p256)
+	critToken, _ := signToken(AlgHS256, map[string]interface{}{"crit": []string{"exp"}, "exp": 1}, map[string]interface{}{}, secret)
+	// 篡改载荷或签名
+	parts := strings.Split(hsToken, ".")
+	tamperedPayload := parts[0] + "." + b64([]byte(`{"sub":"admin"}`)) + "." + parts[2]
+	sig, _ := b64Decode(parts[2])
+	sig[len(sig)-1] ^= 1
+	tamperedSig := parts[0] + "." + parts[1] + "." + b64(sig)
+	// 用RSA公钥的字节作为HMAC密钥伪造的令牌
+	confused, _ := signToken(AlgHS256, nil, map[string]interface{}{"sub": "u1"}, rsaKey.PublicKey.N.Bytes())
+
+	tests := []struct {
+		name  string
+		token string
+		algs  []string
+		key   interface{}
+		want  error
+	}{
+		{"alg none", unsignedToken(`{"alg":"none"}`), nil, secret, ErrTokenSignatureInvalid},
+		{"alg None", unsignedToken(`{"alg":"None"}`), nil, secret, ErrTokenSignatureInvalid},
+		{"missing alg", unsignedToken(`{"typ":"JWT"}`), nil, secret, ErrTokenSignatureInvalid},
+		{"unknown crit", critToken, nil, secret, ErrTokenUnverifiable},
+		{"alg not allowed", rsToken, []string{AlgHS256}, &rsaKey.PublicKey, ErrTokenSignatureInvalid},
+		{"HS256 with RSA public key", confused, nil, &rsaKey.PublicKey, ErrInvalidKey},
+		{"RS256 with HMAC secret", rsToken, nil, secret, ErrInvalidKey},
+		{"ES256 with RSA public key", esToken, nil, &rsaKey.PublicKey, ErrInvalidKey},
+		{"tampered payload", tamperedPayload, nil, secret, ErrTokenSignatureInvalid},
+		{"tampered signature", tamperedSig, nil, secret, ErrTokenSignatureInvalid},
+		{"wrong secret", hsToken, nil, []byte("other"), ErrTokenSignatureInvalid},
+		{"two segments", parts[0] + "." + parts[1], nil, secret, ErrTokenMalformed},
+		{"bad header encoding", "!!." + parts[1] + "." + parts[2], nil, secret, ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]interface{}{}
+			err := parseToken(tt.token, &claims, tt.algs, staticKey(tt.key))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignRejectsMismatchedKey(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := signToken(AlgES384, nil, map[string]interface{}{}, p256); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ES384 with P-256 key: %v, want ErrInvalidKey", err)
+	}
+	if _, err := signToken(AlgHS256, nil, map[string]interface{}{}, p256); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("HS256 with ECDSA key: %v, want ErrInvalidKey", err)
+	}
+	if _, err := signToken("none", nil, map[string]interface{}{}, nil); err == nil {
+		t.Error("signing with alg none succeeded")
+	}
+}
+
+func TestParseTokenTimeClaims(t *testing.T) {
+	const key = "secret"
+	now := time.Now()
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		ok     bool
+	}{
+		{"valid", map[string]interface{}{"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(-time.Minute).Unix()}, true},
+		{"expired", map[string]interface{}{"exp": now.Add(-time.Second).Unix()}, false},
+		{"not valid yet", map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}, false},
+		{"issued in the future", map[string]interface{}{"iat": now.Add(time.Hour).Unix()}, false},
+		// CreateToken 写入的是字符串，不做时间校验
+		{"string exp", map[string]interface{}{"exp": "1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := signToken(AlgHS256, nil, tt.claims, []byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := ParseToken(token, key); ok != tt.ok {
+				t.Errorf("ParseToken ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCreateParseTokenLegacy(t *testing.T) {
+	token := CreateToken(map[string]string{"uid": "42"}, "secret")
+	claims, ok := ParseToken(token, "secret")
+	if !ok || claims["uid"] != "42" {
+		t.Fatalf("ParseToken = %v, %v", claims, ok)
+	}
+	if _, ok := ParseToken(token, "other"); ok {
+		t.Error("ParseToken accepted a token signed with another key")
+	}
+	if CreateToken(map[string]string{"uid": "42"}) != "" {
+		t.Error("CreateToken used the default key without SetAllowDefaultKey")
+	}
+}
+
+func TestDecodeUnverified(t *testing.T) {
+	header, claims, err := DecodeUnverified(rfcA3Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header["alg"] != AlgES256 {
+		t.Errorf("alg = %v, want ES256", header["alg"])
+	}
+	if claims["iss"] != "joe" {
+		t.Errorf("iss = %v, want joe", claims["iss"])
+	}
+	if _, _, err := DecodeUnverified("a.b"); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("error = %v, want ErrTokenMalformed", err)
+	}
+}
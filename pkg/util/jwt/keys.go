@@ -0,0 +1,188 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/security"
+)
+
+// 签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgHS384 = "HS384"
+	AlgHS512 = "HS512"
+	AlgRS256 = "RS256"
+	AlgRS384 = "RS384"
+	AlgRS512 = "RS512"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+	AlgES512 = "ES512"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key 签名/验签密钥
+type Key struct {
+	// ID 写入令牌头的kid
+	ID        string
+	Algorithm string
+	// Private 签名密钥：HMAC为[]byte，其余为 *rsa.PrivateKey、*ecdsa.PrivateKey、ed25519.PrivateKey，只用于验签时为nil
+	Private interface{}
+	// Public 验签密钥：HMAC为[]byte，其余为 *rsa.PublicKey、*ecdsa.PublicKey、ed25519.PublicKey
+	Public interface{}
+}
+
+// CanSign 是否可以用于签名
+func (k *Key) CanSign() bool {
+	return k != nil && k.Private != nil
+}
+
+// NewHMACKey 创建HMAC密钥，alg 为 HS256/HS384/HS512
+func NewHMACKey(kid, alg string, secret []byte) (*Key, error) {
+	switch alg {
+	case AlgHS256, AlgHS384, AlgHS512:
+	default:
+		return nil, fmt.Errorf("jwt: %s is not a HMAC algorithm", alg)
+	}
+	if len(secret) == 0 {
+		return nil, ErrInvalidKey
+	}
+	return &Key{ID: kid, Algorithm: alg, Private: secret, Public: secret}, nil
+}
+
+// NewSigningKey 根据私钥创建签名密钥，alg为空时按密钥类型取 RS256/ES256(按曲线)/EdDSA
+func NewSigningKey(kid, alg string, priv crypto.Signer) (*Key, error) {
+	key := &Key{ID: kid, Algorithm: alg, Private: priv, Public: priv.Public()}
+	if err := key.check(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewVerifyKey 根据公钥创建验签密钥，alg为空时按密钥类型推断
+func NewVerifyKey(kid, alg string, pub crypto.PublicKey) (*Key, error) {
+	key := &Key{ID: kid, Algorithm: alg, Public: pub}
+	if err := key.check(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewRSAKeyFromPEM 使用 security.ParsePKCS1Or8PrivKey 解析RSA私钥，alg 为 RS256/RS384/RS512
+func NewRSAKeyFromPEM(kid, alg string, privPEM []byte) (*Key, error) {
+	priv, err := security.ParsePKCS1Or8PrivKey(privPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return NewSigningKey(kid, alg, priv)
+}
+
+// NewRSAPublicKeyFromPEM 使用 security.ParsePublicKey 解析RSA公钥
+func NewRSAPublicKeyFromPEM(kid, alg string, pubPEM []byte) (*Key, error) {
+	pub, err := security.ParsePublicKey(pubPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return NewVerifyKey(kid, alg, pub)
+}
+
+// NewPrivateKeyFromPEM 解析PKCS8/PKCS1/SEC1格式的RSA、ECDSA、Ed25519私钥
+func NewPrivateKeyFromPEM(kid, alg string, privPEM []byte) (*Key, error) {
+	block, _ := pem.Decode(privPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: pem decode error", ErrInvalidKey)
+	}
+	var priv interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return NewRSAKeyFromPEM(kid, alg, privPEM)
+	case "EC PRIVATE KEY":
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidKey, priv)
+	}
+	return NewSigningKey(kid, alg, signer)
+}
+
+// NewPublicKeyFromPEM 解析PKIX格式的RSA、ECDSA、Ed25519公钥
+func NewPublicKeyFromPEM(kid, alg string, pubPEM []byte) (*Key, error) {
+	block, _ := pem.Decode(pubPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: pem decode error", ErrInvalidKey)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	return NewVerifyKey(kid, alg, pub)
+}
+
+// check 校验算法和密钥类型是否匹配，算法为空时按密钥类型推断
+func (k *Key) check() error {
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		if k.Algorithm == "" {
+			k.Algorithm = AlgRS256
+		}
+		switch k.Algorithm {
+		case AlgRS256, AlgRS384, AlgRS512:
+		default:
+			return fmt.Errorf("jwt: algorithm %s does not match RSA key", k.Algorithm)
+		}
+		if pub.N.BitLen() < 2048 {
+			return fmt.Errorf("%w: RSA key must be at least 2048 bits", ErrInvalidKey)
+		}
+	case *ecdsa.PublicKey:
+		alg := ecdsaAlgorithm(pub.Curve)
+		if alg == "" {
+			return fmt.Errorf("%w: unsupported elliptic curve", ErrInvalidKey)
+		}
+		if k.Algorithm == "" {
+			k.Algorithm = alg
+		}
+		if k.Algorithm != alg {
+			return fmt.Errorf("jwt: algorithm %s does not match curve %s", k.Algorithm, pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		if k.Algorithm == "" {
+			k.Algorithm = AlgEdDSA
+		}
+		if k.Algorithm != AlgEdDSA {
+			return fmt.Errorf("jwt: algorithm %s does not match Ed25519 key", k.Algorithm)
+		}
+	case nil:
+		return ErrInvalidKey
+	default:
+		return fmt.Errorf("%w: unsupported public key type %T", ErrInvalidKey, pub)
+	}
+	return nil
+}
+
+func ecdsaAlgorithm(curve elliptic.Curve) string {
+	switch curve {
+	case elliptic.P256():
+		return AlgES256
+	case elliptic.P384():
+		return AlgES384
+	case elliptic.P521():
+		return AlgES512
+	}
+	return ""
+}
+
+// errNoVerifyKey 找不到验签密钥
+var errNoVerifyKey = fmt.Errorf("%w: no key found to verify token", ErrTokenUnverifiable)
@@ -0,0 +1,172 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+)
+
+// JWKSPath JWKS公开地址
+const JWKSPath = "/.well-known/jwks.json"
+
+// KeyResolver 按令牌头的kid和alg查找验签密钥
+type KeyResolver interface {
+	Lookup(kid, alg string) (*Key, error)
+}
+
+// retiredKey 已轮换下来的密钥，宽限期内仍可验签
+type retiredKey struct {
+	key       *Key
+	expiresAt time.Time
+}
+
+// KeySet 签名密钥集合，包含一个当前密钥和宽限期内的旧密钥，并发安全
+type KeySet struct {
+	mu      sync.RWMutex
+	active  *Key
+	retired []retiredKey
+	now     func() time.Time
+}
+
+// NewKeySet 创建密钥集合，active 必须可以签名且带kid
+func NewKeySet(active *Key) (*KeySet, error) {
+	if err := checkActiveKey(active); err != nil {
+		return nil, err
+	}
+	return &KeySet{active: active, now: time.Now}, nil
+}
+
+func checkActiveKey(k *Key) error {
+	if !k.CanSign() {
+		return errors.New("jwt: active key must be able to sign")
+	}
+	if k.ID == "" {
+		return errors.New("jwt: active key must have a kid")
+	}
+	return nil
+}
+
+// Active 当前签名密钥
+func (s *KeySet) Active() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Rotate 切换签名密钥，旧密钥在 grace 时间内仍可用于验签
+func (s *KeySet) Rotate(next *Key, grace time.Duration) error {
+	if err := checkActiveKey(next); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.pruneLocked(now)
+	if grace > 0 {
+		s.retired = append(s.retired, retiredKey{key: s.active, expiresAt: now.Add(grace)})
+	}
+	s.active = next
+	return nil
+}
+
+// Retire 提前移除指定kid的旧密钥，比如密钥泄露时
+func (s *KeySet) Retire(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	retired := s.retired[:0]
+	for _, r := range s.retired {
+		if r.key.ID != kid {
+			retired = append(retired, r)
+		}
+	}
+	s.retired = retired
+}
+
+// Keys 当前可用于验签的密钥，当前密钥在前
+func (s *KeySet) Keys() []*Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	keys := []*Key{s.active}
+	for _, r := range s.retired {
+		if now.Before(r.expiresAt) {
+			keys = append(keys, r.key)
+		}
+	}
+	return keys
+}
+
+// Lookup 实现 KeyResolver
+func (s *KeySet) Lookup(kid, alg string) (*Key, error) {
+	for _, k := range s.Keys() {
+		if k.ID == kid && k.Algorithm == alg {
+			return k, nil
+		}
+	}
+	return nil, errNoVerifyKey
+}
+
+// JWKS 导出可公开的公钥，HMAC密钥不会导出
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range s.Keys() {
+		if jwk, ok := k.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// Handler 返回JWKS的gin处理函数，maxAge 为客户端缓存时间
+//
+//	r.GET(jwt.JWKSPath, keySet.Handler(5*time.Minute))
+func (s *KeySet) Handler(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxAge > 0 {
+			c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+		}
+		c.JSON(http.StatusOK, s.JWKS())
+	}
+}
+
+// StartRotation 每隔 interval 用 generate 生成新密钥并轮换，旧密钥保留 grace 时间
+func (s *KeySet) StartRotation(interval, grace time.Duration, generate func() (*Key, error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				key, err := generate()
+				if err == nil {
+					err = s.Rotate(key, grace)
+				}
+				if err != nil {
+					log.Errorf("rotate jwt signing key error: %s", err)
+					continue
+				}
+				log.Warnf("jwt signing key rotated to %s", key.ID)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (s *KeySet) pruneLocked(now time.Time) {
+	retired := s.retired[:0]
+	for _, r := range s.retired {
+		if now.Before(r.expiresAt) {
+			retired = append(retired, r)
+		}
+	}
+	s.retired = retired
+}
@@ -0,0 +1,266 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/utils"
+)
+
+// ManagerConfig 令牌管理配置
+type ManagerConfig struct {
+	// Algorithm HMAC签名算法，默认 HS256，支持 HS256/HS384/HS512，设置 SigningKey 时忽略
+	Algorithm string
+	// Key HMAC密钥，设置 SigningKey 时忽略
+	Key []byte
+	// SigningKey 签名密钥，支持HMAC、RSA、ECDSA、Ed25519，ID不为空时写入令牌头kid
+	SigningKey *Key
+	// VerifyKeys 额外的验签密钥，令牌头带kid时按kid匹配，可让多个密钥同时生效
+	VerifyKeys []*Key
+	// KeySet 可轮换的密钥集合，未设置 SigningKey 时用其当前密钥签名，验签时按kid查找
+	KeySet *KeySet
+	// Resolver 额外的验签密钥来源，如 JWKSClient
+	Resolver KeyResolver
+	// Revocation 撤销列表，设置后解析时检查jti是否已撤销
+	Revocation RevocationStore
+	// Issuer 签发时写入iss，解析时校验iss
+	Issuer string
+	// Audience 签发时写入aud，解析时要求aud包含其中之一
+	Audience []string
+	// TTL 令牌有效期，默认2小时
+	TTL time.Duration
+	// Leeway 校验exp/nbf/iat时允许的时钟偏差
+	Leeway time.Duration
+	// Now 当前时间，默认 time.Now，测试时可替换
+	Now func() time.Time
+}
+
+// Manager 令牌签发和解析
+type Manager struct {
+	cfg ManagerConfig
+	// keys 验签密钥，包含签名密钥
+	keys []*Key
+	// algs 允许的算法，由静态验签密钥决定，有 KeySet 或 Resolver 时为空，按查到的密钥校验
+	algs []string
+	// resolvers 按kid动态查找验签密钥
+	resolvers []KeyResolver
+	// tokenType 令牌头typ，用于区分访问令牌和刷新令牌，为空时是普通JWT
+	tokenType string
+}
+
+// NewManager 创建令牌管理器，不会使用默认密钥
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if cfg.SigningKey == nil && len(cfg.Key) > 0 {
+		if cfg.Algorithm == "" {
+			cfg.Algorithm = AlgHS256
+		}
+		key, err := NewHMACKey("", cfg.Algorithm, cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SigningKey = key
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 2 * time.Hour
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	m := &Manager{cfg: cfg}
+	if cfg.SigningKey != nil {
+		m.keys = append(m.keys, cfg.SigningKey)
+	}
+	m.keys = append(m.keys, cfg.VerifyKeys...)
+	if cfg.KeySet != nil {
+		m.resolvers = append(m.resolvers, cfg.KeySet)
+	}
+	if cfg.Resolver != nil {
+		m.resolvers = append(m.resolvers, cfg.Resolver)
+	}
+	dynamic := len(m.resolvers) > 0
+	if len(m.keys) == 0 && !dynamic {
+		return nil, ErrInvalidKey
+	}
+	for _, k := range m.keys {
+		if k == nil || k.Public == nil {
+			return nil, ErrInvalidKey
+		}
+		if !dynamic && !containsAlg(m.algs, k.Algorithm) {
+			m.algs = append(m.algs, k.Algorithm)
+		}
+	}
+	return m, nil
+}
+
+// Sign 签发令牌，claims中未设置的iss、aud、iat、nbf、exp、jti会按配置填充
+func (m *Manager) Sign(claims Claims) (string, error) {
+	if claims == nil {
+		return "", errors.New("jwt: claims is nil")
+	}
+	key := m.cfg.SigningKey
+	if key == nil && m.cfg.KeySet != nil {
+		key = m.cfg.KeySet.Active()
+	}
+	if !key.CanSign() {
+		return "", fmt.Errorf("%w: no signing key", ErrInvalidKey)
+	}
+	m.fillClaims(claims.GetRegisteredClaims())
+	header := map[string]interface{}{}
+	if key.ID != "" {
+		header["kid"] = key.ID
+	}
+	if m.tokenType != "" {
+		header["typ"] = m.tokenType
+	}
+	return signToken(key.Algorithm, header, claims, key.Private)
+}
+
+// Parse 解析令牌到claims，校验签名、算法、时间、签发者和受众
+func (m *Manager) Parse(token string, claims Claims) error {
+	if claims == nil {
+		return errors.New("jwt: claims is nil")
+	}
+	var typ string
+	err := parseToken(token, claims, m.algs, func(header map[string]interface{}) (interface{}, error) {
+		typ, _ = header["typ"].(string)
+		return m.lookupKey(header)
+	})
+	if err != nil {
+		return err
+	}
+	if !m.acceptType(typ) {
+		return ErrTokenInvalidType
+	}
+	rc := claims.GetRegisteredClaims()
+	if err := m.validate(rc); err != nil {
+		return err
+	}
+	if m.cfg.Revocation != nil && rc.ID != "" {
+		revoked, err := m.cfg.Revocation.IsRevoked(rc.ID)
+		if err != nil {
+			return fmt.Errorf("%w: check revocation error: %s", ErrTokenUnverifiable, err)
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+// Revoke 撤销令牌直到其过期，需要配置 Revocation，已过期或已撤销的令牌直接返回nil
+func (m *Manager) Revoke(token string) error {
+	if m.cfg.Revocation == nil {
+		return errors.New("jwt: revocation store is not configured")
+	}
+	var rc RegisteredClaims
+	if err := m.Parse(token, &rc); err != nil {
+		// Parse 在签名和类型校验通过后才检查exp，过期的令牌本身已不可用，无需再撤销
+		if errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrTokenExpired) {
+			return nil
+		}
+		return err
+	}
+	return m.revoke(&rc)
+}
+
+func (m *Manager) revoke(rc *RegisteredClaims) error {
+	if rc.ID == "" {
+		return fmt.Errorf("%w: token has no jti", ErrTokenMalformed)
+	}
+	// 没有exp的令牌永久有效，撤销也需要一直保存
+	until := time.Unix(1<<62, 0)
+	if rc.ExpiresAt != nil {
+		until = rc.ExpiresAt.Add(m.cfg.Leeway)
+	}
+	return m.cfg.Revocation.Revoke(rc.ID, until)
+}
+
+// acceptType 普通JWT兼容typ为空或JWT，其余类型必须完全一致
+func (m *Manager) acceptType(typ string) bool {
+	if m.tokenType == "" {
+		return typ == "" || strings.EqualFold(typ, "JWT")
+	}
+	return typ == m.tokenType
+}
+
+// ParseClaims 解析令牌为指定类型的claims
+//
+//	claims, err := jwt.ParseClaims[UserClaims](manager, token)
+func ParseClaims[T any, PT interface {
+	*T
+	Claims
+}](m *Manager, token string) (*T, error) {
+	claims := PT(new(T))
+	if err := m.Parse(token, claims); err != nil {
+		return nil, err
+	}
+	return (*T)(claims), nil
+}
+
+// lookupKey 按令牌头的alg和kid查找验签密钥，未带kid时使用第一个算法匹配的静态密钥
+func (m *Manager) lookupKey(header map[string]interface{}) (interface{}, error) {
+	alg, _ := header["alg"].(string)
+	kid, _ := header["kid"].(string)
+	for _, k := range m.keys {
+		if k.Algorithm != alg {
+			continue
+		}
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		return k.Public, nil
+	}
+	if kid == "" {
+		return nil, errNoVerifyKey
+	}
+	err := errNoVerifyKey
+	for _, r := range m.resolvers {
+		var k *Key
+		k, err = r.Lookup(kid, alg)
+		if err != nil {
+			continue
+		}
+		if k.Algorithm != alg || k.Public == nil {
+			return nil, errNoVerifyKey
+		}
+		return k.Public, nil
+	}
+	return nil, err
+}
+
+func containsAlg(algs []string, alg string) bool {
+	for _, a := range algs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) fillClaims(rc *RegisteredClaims) {
+	now := m.cfg.Now()
+	if rc.Issuer == "" {
+		rc.Issuer = m.cfg.Issuer
+	}
+	if len(rc.Audience) == 0 && len(m.cfg.Audience) > 0 {
+		rc.Audience = append(Audience(nil), m.cfg.Audience...)
+	}
+	if rc.IssuedAt == nil {
+		rc.IssuedAt = NewNumericDate(now)
+	}
+	if rc.NotBefore == nil {
+		rc.NotBefore = NewNumericDate(now)
+	}
+	if rc.ExpiresAt == nil {
+		rc.ExpiresAt = NewNumericDate(now.Add(m.cfg.TTL))
+	}
+	if rc.ID == "" {
+		rc.ID = utils.SerialNumber()
+	}
+}
+
+func (m *Manager) validate(rc *RegisteredClaims) error {
+	return rc.validate(m.cfg.Now(), m.cfg.Leeway, m.cfg.Issuer, m.cfg.Audience)
+}
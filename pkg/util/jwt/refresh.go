@@ -0,0 +1,225 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/util/utils"
+)
+
+// RefreshTokenType 刷新令牌头的typ，访问令牌的 Manager 不会接受
+const RefreshTokenType = "refresh+jwt"
+
+// TokenPair 访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn 访问令牌有效期，单位秒
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// RefreshClaims 刷新令牌的claims，Family 为同一次登录派生出的所有刷新令牌共用的标识
+type RefreshClaims struct {
+	RegisteredClaims
+	Family string `json:"fam"`
+}
+
+// RefreshStore 记录每个令牌族当前有效的刷新令牌，用于检测刷新令牌重复使用
+type RefreshStore interface {
+	// Save 记录新令牌族的刷新令牌
+	Save(family, jti string, ttl time.Duration) error
+	// Rotate 原子地将令牌族的当前刷新令牌从 old 替换为 new，old 不是当前令牌时返回 ErrRefreshTokenReused
+	Rotate(family, old, new string, ttl time.Duration) error
+	// RevokeFamily 撤销整个令牌族
+	RevokeFamily(family string) error
+}
+
+// TokenServiceConfig 令牌对签发配置
+type TokenServiceConfig struct {
+	// Manager 签发和解析访问令牌，刷新令牌使用相同的密钥但typ不同
+	Manager *Manager
+	// RefreshTTL 刷新令牌有效期，默认7天，每次刷新重新计算
+	RefreshTTL time.Duration
+	// Store 刷新令牌存储，默认内存存储
+	Store RefreshStore
+}
+
+// TokenService 签发令牌对，刷新时轮换刷新令牌，检测到旧刷新令牌被重复使用时撤销整个令牌族
+type TokenService struct {
+	access  *Manager
+	refresh *Manager
+	store   RefreshStore
+}
+
+// NewTokenService 创建令牌对服务
+func NewTokenService(cfg TokenServiceConfig) (*TokenService, error) {
+	if cfg.Manager == nil {
+		return nil, errors.New("jwt: token service manager is nil")
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRefreshStore()
+	}
+	refresh := *cfg.Manager
+	refresh.cfg.TTL = cfg.RefreshTTL
+	refresh.tokenType = RefreshTokenType
+	return &TokenService{access: cfg.Manager, refresh: &refresh, store: cfg.Store}, nil
+}
+
+// Issue 登录时签发令牌对，claims 为访问令牌的claims
+func (s *TokenService) Issue(claims Claims) (*TokenPair, error) {
+	rc := &RefreshClaims{Family: utils.SerialNumber()}
+	return s.issue(claims, rc, func(jti string) error {
+		return s.store.Save(rc.Family, jti, s.refresh.cfg.TTL)
+	})
+}
+
+// ParseRefresh 解析刷新令牌，不做轮换，可用于加载用户信息
+func (s *TokenService) ParseRefresh(refreshToken string) (*RefreshClaims, error) {
+	var rc RefreshClaims
+	if err := s.refresh.Parse(refreshToken, &rc); err != nil {
+		return nil, err
+	}
+	if rc.Family == "" || rc.ID == "" {
+		return nil, fmt.Errorf("%w: refresh token has no family", ErrTokenMalformed)
+	}
+	return &rc, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌失效
+//
+// claims 为新访问令牌的claims，Subject为空时使用刷新令牌的Subject。
+// 已轮换过的刷新令牌再次使用时撤销整个令牌族并返回 ErrRefreshTokenReused。
+func (s *TokenService) Refresh(refreshToken string, claims Claims) (*TokenPair, error) {
+	old, err := s.ParseRefresh(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if rc := claims.GetRegisteredClaims(); rc.Subject == "" {
+		rc.Subject = old.Subject
+	}
+	next := &RefreshClaims{Family: old.Family}
+	return s.issue(claims, next, func(jti string) error {
+		err := s.store.Rotate(old.Family, old.ID, jti, s.refresh.cfg.TTL)
+		if errors.Is(err, ErrRefreshTokenReused) {
+			if rerr := s.store.RevokeFamily(old.Family); rerr != nil {
+				return fmt.Errorf("%w: revoke family error: %s", err, rerr)
+			}
+		}
+		return err
+	})
+}
+
+// Logout 撤销刷新令牌所在的令牌族，accessToken 不为空且配置了撤销列表时同时撤销访问令牌
+func (s *TokenService) Logout(refreshToken, accessToken string) error {
+	rc, err := s.ParseRefresh(refreshToken)
+	if err != nil {
+		return err
+	}
+	if err := s.store.RevokeFamily(rc.Family); err != nil {
+		return err
+	}
+	if accessToken != "" && s.access.cfg.Revocation != nil {
+		return s.access.Revoke(accessToken)
+	}
+	return nil
+}
+
+// issue 签发令牌对，commit 在两个令牌都签名成功后记录刷新令牌
+func (s *TokenService) issue(claims Claims, rc *RefreshClaims, commit func(jti string) error) (*TokenPair, error) {
+	if claims == nil {
+		return nil, errors.New("jwt: claims is nil")
+	}
+	access, err := s.access.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	rc.Subject = claims.GetRegisteredClaims().Subject
+	refresh, err := s.refresh.Sign(rc)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(rc.ID); err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.access.cfg.TTL / time.Second),
+	}, nil
+}
+
+// MemoryRefreshStore 内存刷新令牌存储，只适用于单实例
+type MemoryRefreshStore struct {
+	mu       sync.Mutex
+	families map[string]refreshEntry
+	lastGC   time.Time
+	gcEvery  time.Duration
+}
+
+type refreshEntry struct {
+	jti       string
+	expiresAt time.Time
+}
+
+// NewMemoryRefreshStore 创建内存刷新令牌存储
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		families: make(map[string]refreshEntry),
+		lastGC:   time.Now(),
+		gcEvery:  time.Minute,
+	}
+}
+
+// Save 记录新令牌族的刷新令牌
+func (s *MemoryRefreshStore) Save(family, jti string, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gcLocked(now)
+	s.families[family] = refreshEntry{jti: jti, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Rotate 替换令牌族的当前刷新令牌
+func (s *MemoryRefreshStore) Rotate(family, old, new string, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gcLocked(now)
+	entry, ok := s.families[family]
+	if !ok || !now.Before(entry.expiresAt) {
+		return ErrTokenRevoked
+	}
+	if entry.jti != old {
+		return ErrRefreshTokenReused
+	}
+	s.families[family] = refreshEntry{jti: new, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// RevokeFamily 撤销整个令牌族
+func (s *MemoryRefreshStore) RevokeFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.families, family)
+	return nil
+}
+
+func (s *MemoryRefreshStore) gcLocked(now time.Time) {
+	if now.Sub(s.lastGC) <= s.gcEvery {
+		return
+	}
+	for k, e := range s.families {
+		if now.After(e.expiresAt) {
+			delete(s.families, k)
+		}
+	}
+	s.lastGC = now
+}
@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhuzhaoman/pkgutil/pkg/response"
+)
+
+// RefreshRequest 刷新和登出的请求体，支持JSON和表单
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
+}
+
+// ClaimsFunc 根据刷新令牌生成新访问令牌的claims，可在此重新加载用户并检查是否被禁用
+type ClaimsFunc func(c *gin.Context, rc *RefreshClaims) (Claims, error)
+
+// RefreshHandler /token/refresh 处理函数，成功时返回 TokenPair
+//
+//	r.POST("/token/refresh", tokens.RefreshHandler(func(c *gin.Context, rc *jwt.RefreshClaims) (jwt.Claims, error) {
+//		return &UserClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: rc.Subject}}, nil
+//	}))
+func (s *TokenService) RefreshHandler(claimsFn ClaimsFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBind(&req); err != nil {
+			response.Abort(c, response.ErrBadRequest.WithMessage("refresh_token is required").WithCause(err))
+			return
+		}
+		rc, err := s.ParseRefresh(req.RefreshToken)
+		if err != nil {
+			response.Abort(c, response.ErrUnauthorized.WithCause(err))
+			return
+		}
+		claims, err := claimsFn(c, rc)
+		if err != nil {
+			response.Abort(c, response.FromError(err))
+			return
+		}
+		pair, err := s.Refresh(req.RefreshToken, claims)
+		if err != nil {
+			if isTokenError(err) {
+				response.Abort(c, response.ErrUnauthorized.WithCause(err))
+			} else {
+				response.Abort(c, response.ErrInternal.WithCause(err))
+			}
+			return
+		}
+		response.OK(c, pair)
+	}
+}
+
+// LogoutHandler 登出处理函数，撤销刷新令牌所在令牌族，Authorization 头中的访问令牌一并撤销
+func (s *TokenService) LogoutHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBind(&req); err != nil {
+			response.Abort(c, response.ErrBadRequest.WithMessage("refresh_token is required").WithCause(err))
+			return
+		}
+		var access string
+		if auth := c.GetHeader("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			access = strings.TrimSpace(auth[7:])
+		}
+		if err := s.Logout(req.RefreshToken, access); err != nil {
+			response.Abort(c, response.ErrUnauthorized.WithCause(err))
+			return
+		}
+		response.OK(c, nil)
+	}
+}
+
+// isTokenError 是否为令牌本身的错误，需要客户端重新登录
+func isTokenError(err error) bool {
+	for _, target := range []error{
+		ErrTokenMalformed, ErrTokenUnverifiable, ErrTokenSignatureInvalid, ErrTokenExpired,
+		ErrTokenNotValidYet, ErrTokenUsedBeforeIssued, ErrTokenInvalidIssuer, ErrTokenInvalidAudience,
+		ErrTokenRevoked, ErrTokenInvalidType, ErrRefreshTokenReused,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
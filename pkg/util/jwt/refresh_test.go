@@ -0,0 +1,78 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestTokenService(t *testing.T, now *time.Time) (*TokenService, *Manager) {
+	t.Helper()
+	m, err := NewManager(ManagerConfig{
+		Key:        []byte("0123456789abcdef0123456789abcdef"),
+		TTL:        time.Hour,
+		Revocation: NewMemoryRevocationStore(),
+		Now:        func() time.Time { return *now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewTokenService(TokenServiceConfig{Manager: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, m
+}
+
+func TestTokenServiceLogoutWithExpiredAccessToken(t *testing.T) {
+	now := time.Now()
+	s, m := newTestTokenService(t, &now)
+	pair, err := s.Issue(&RegisteredClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := m.Parse(pair.AccessToken, &RegisteredClaims{}); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("access token: %v, want ErrTokenExpired", err)
+	}
+	if err := s.Logout(pair.RefreshToken, pair.AccessToken); err != nil {
+		t.Fatalf("Logout with expired access token: %v", err)
+	}
+	if _, err := s.Refresh(pair.RefreshToken, &RegisteredClaims{}); err == nil {
+		t.Error("refresh token still usable after logout")
+	}
+}
+
+func TestManagerRevoke(t *testing.T) {
+	now := time.Now()
+	_, m := newTestTokenService(t, &now)
+	token, err := m.Sign(&RegisteredClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Revoke(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Parse(token, &RegisteredClaims{}); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("parse revoked token: %v, want ErrTokenRevoked", err)
+	}
+	// 重复撤销和撤销已过期的令牌都视为成功
+	if err := m.Revoke(token); err != nil {
+		t.Errorf("revoke twice: %v", err)
+	}
+	expired, _ := m.Sign(&RegisteredClaims{Subject: "bob"})
+	now = now.Add(2 * time.Hour)
+	if err := m.Revoke(expired); err != nil {
+		t.Errorf("revoke expired token: %v", err)
+	}
+	// 过期令牌仍然校验签名
+	tampered := expired[:len(expired)-2] + "AA"
+	if tampered == expired {
+		tampered = expired[:len(expired)-2] + "BB"
+	}
+	if err := m.Revoke(tampered); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("revoke tampered expired token: %v, want ErrTokenSignatureInvalid", err)
+	}
+}
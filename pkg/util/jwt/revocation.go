@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+)
+
+// RevocationStore 按jti记录已撤销的令牌，Manager 解析时检查
+type RevocationStore interface {
+	// Revoke 撤销令牌，until 之后令牌本身已过期，可以不再保存
+	Revoke(jti string, until time.Time) error
+	// IsRevoked 令牌是否已撤销
+	IsRevoked(jti string) (bool, error)
+}
+
+// MemoryRevocationStore 内存撤销列表，只适用于单实例
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	items   map[string]time.Time
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// NewMemoryRevocationStore 创建内存撤销列表
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		items:   make(map[string]time.Time),
+		lastGC:  time.Now(),
+		gcEvery: time.Minute,
+	}
+}
+
+// Revoke 撤销令牌
+func (s *MemoryRevocationStore) Revoke(jti string, until time.Time) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gcLocked(now)
+	if until.After(now) {
+		s.items[jti] = until
+	}
+	return nil
+}
+
+// IsRevoked 令牌是否已撤销
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gcLocked(now)
+	until, ok := s.items[jti]
+	return ok && now.Before(until), nil
+}
+
+func (s *MemoryRevocationStore) gcLocked(now time.Time) {
+	if now.Sub(s.lastGC) <= s.gcEvery {
+		return
+	}
+	for k, until := range s.items {
+		if now.After(until) {
+			delete(s.items, k)
+		}
+	}
+	s.lastGC = now
+}
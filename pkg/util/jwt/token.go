@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+)
+
+const jwtKey = "aabbcc"
+
+// allowDefaultKey 是否允许未传入密钥时使用内置默认密钥
+var allowDefaultKey bool
+
+// SetAllowDefaultKey 允许 CreateToken/ParseToken 未传入密钥时使用内置默认密钥，仅用于测试环境
+func SetAllowDefaultKey(allow bool) {
+	allowDefaultKey = allow
+}
+
+// resolveKey 返回传入的密钥，未传入且不允许默认密钥时返回false
+func resolveKey(keys []string) (string, bool) {
+	if len(keys) > 0 && keys[0] != "" {
+		return keys[0], true
+	}
+	if !allowDefaultKey {
+		log.Errorf("jwt: no key provided and default key is not allowed, use SetAllowDefaultKey(true) to restore the old behavior")
+		return "", false
+	}
+	return jwtKey, true
+}
+
+// 创建token
+//
+// 兼容性变更：未传入密钥时不再使用内置默认密钥，记录错误日志并返回空字符串，
+// 需要旧行为时调用 SetAllowDefaultKey(true)
+func CreateToken(m map[string]string, keys ...string) string {
+	key, ok := resolveKey(keys)
+	if !ok {
+		return ""
+	}
+	tokenString, err := signToken(AlgHS256, nil, m, []byte(key))
+	if err != nil {
+		log.Errorf("create token error: %s", err)
+	}
+	return tokenString
+}
+
+// 解析token
+//
+// 兼容性变更：未传入密钥时不再使用内置默认密钥，记录错误日志并返回false，
+// 需要旧行为时调用 SetAllowDefaultKey(true)
+func ParseToken(tokenString string, keys ...string) (map[string]string, bool) {
+	claims, ok := ParseTokenClaims(tokenString, keys...)
+	if !ok {
+		return nil, false
+	}
+	mapData := make(map[string]string)
+	for index, val := range claims {
+		mapData[index] = fmt.Sprintf("%v", val)
+	}
+	return mapData, true
+}
+
+// ParseTokenClaims 与 ParseToken 相同，但保留claim的JSON类型，数组为 []interface{}，数字为 float64
+func ParseTokenClaims(tokenString string, keys ...string) (map[string]interface{}, bool) {
+	key, ok := resolveKey(keys)
+	if !ok {
+		return nil, false
+	}
+	claims := make(map[string]interface{})
+	err := parseToken(tokenString, &claims, []string{AlgHS256, AlgHS384, AlgHS512}, func(map[string]interface{}) (interface{}, error) {
+		return []byte(key), nil
+	})
+	if err != nil || validateMapClaims(claims, time.Now()) != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// validateMapClaims 数字类型的exp、nbf、iat才做校验，CreateToken 写入的字符串值不校验
+func validateMapClaims(claims map[string]interface{}, now time.Time) error {
+	unix := now.Unix()
+	if exp, ok := claims["exp"].(float64); ok && unix >= int64(exp) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && unix < int64(nbf) {
+		return ErrTokenNotValidYet
+	}
+	if iat, ok := claims["iat"].(float64); ok && unix < int64(iat) {
+		return ErrTokenUsedBeforeIssued
+	}
+	return nil
+}
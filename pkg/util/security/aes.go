@@ -1,23 +1,19 @@
 package security
 
 import (
-	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
-	"fmt"
 	"github.com/zhuzhaoman/pkgutil/pkg/log"
-	"io"
 	"strings"
 )
 
-// AESCBCMode 如果key位base64编码过的字符串
+// AESCBCMode 如果key位base64编码过的字符串，Err 为最近一次调用的错误，需要错误返回值时使用 AESCBC
 type AESCBCMode struct {
 	Key    []byte
 	Err    error
 	sysAES *AESCBCMode
+	// keyErr 构造时密钥解码的错误，此时 Key 可能只解码了一部分，之后每次调用都返回该错误
+	keyErr error
 }
 
 // NewAESCBCEncrypt 创建一个 AES 加密对象，使用 CBC 模式
@@ -29,8 +25,9 @@ func NewAESCBCEncrypt(b64Key, sysKey string) *AESCBCMode {
 	}
 
 	return &AESCBCMode{
-		Key: bytesKey,
-		Err: err,
+		Key:    bytesKey,
+		Err:    err,
+		keyErr: err,
 		sysAES: &AESCBCMode{
 			Key: []byte(sysKey),
 		},
@@ -38,7 +35,8 @@ func NewAESCBCEncrypt(b64Key, sysKey string) *AESCBCMode {
 }
 
 // DcyAndUseSysKeyEcy
-// decrypted 解密后的明文 encrypted 使用新key后的密文
+// decrypted 解密后的明文 encrypted 使用新key后的密文，解密失败时两者都返回原文 ct
+// 批量轮换密钥使用 ReEncryptor，失败会记录到报告中而不是原样返回
 func (a *AESCBCMode) DcyAndUseSysKeyEcy(ct, sysKey string) (decrypted, encrypted string) {
 	if a.sysAES == nil {
 		a.sysAES = &AESCBCMode{Key: []byte(sysKey)}
@@ -49,7 +47,7 @@ func (a *AESCBCMode) DcyAndUseSysKeyEcy(ct, sysKey string) (decrypted, encrypted
 
 	if a.Err != nil {
 		log.Errorf("Decrypt the string error, string is %s, error is %s", ct, a.Err.Error())
-		return decrypted, decrypted
+		return ct, ct
 	}
 	// encrypt
 	encrypted = a.sysAES.Encrypt(decrypted)
@@ -75,110 +73,69 @@ func (a *AESCBCMode) AesCbcEncrypt(ct, sysKey string) string {
 	return encrypted
 }
 
-// Encrypt cbc mode
+// Encrypt cbc mode，失败时返回空字符串，错误保存在 Err 中，需要错误返回值时使用 AESCBC
 func (a *AESCBCMode) Encrypt(pt string) string {
-	if a.Err != nil {
-		return pt
-	}
-	plaintext := pkcs7Padding([]byte(pt), aes.BlockSize)
-
-	if len(plaintext)%aes.BlockSize != 0 {
-		a.Err = fmt.Errorf("%s : plaintext is not a multiple of the block size", pt)
-		log.Error(a.Err.Error())
-		return pt
-	}
-
-	block, err := aes.NewCipher(a.Key)
+	c, err := a.cipher()
 	if err != nil {
 		a.Err = err
-		log.Error(a.Err.Error())
-		return pt
+		log.Errorf("create the block error, the error is %s", err)
+		return ""
 	}
-
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	// 随机生成16个字节数组
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		a.Err = err
-		log.Errorf("generate the rand num error, error is %s", a.Err.Error())
-		return pt
+	ciphertext, err := c.Encrypt([]byte(pt))
+	a.Err = err
+	if err != nil {
+		log.Errorf("cbc encrypt error, the error is %s", err)
+		return ""
 	}
-	mode := cipher.NewCBCEncrypter(block, iv)
-	mode.CryptBlocks(ciphertext[aes.BlockSize:], plaintext)
-
 	return base64.StdEncoding.EncodeToString(ciphertext)
 }
 
-// Decrypt cbc mode
+// Decrypt cbc mode，失败时返回空字符串，错误保存在 Err 中，需要错误返回值时使用 AESCBC
 func (a *AESCBCMode) Decrypt(ct string) string {
-
-	if a.Err != nil {
-		return ct
-	}
-	defer func() {
-		if err := recover(); err != nil {
-			a.Err = err.(error)
-		}
-	}()
-
-	ct = strings.TrimSpace(ct)
-	ciphertext, err := base64.StdEncoding.DecodeString(ct)
+	c, err := a.cipher()
 	if err != nil {
 		a.Err = err
-		log.Errorf("Decode the string error, the string is %s, error is %s", ct, err.Error())
-		return ct
+		log.Errorf("create the block error, the error is %s", err)
+		return ""
 	}
-	block, err := aes.NewCipher(a.Key)
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ct))
 	if err != nil {
 		a.Err = err
-		log.Errorf("create the block error, the error is %s", err.Error())
-		return ct
-	}
-
-	if len(ciphertext) < aes.BlockSize {
-		a.Err = fmt.Errorf("%s : ciphertext too short", ct)
-		log.Error(a.Err.Error())
-		return ct
+		log.Errorf("Decode the string error, the string is %s, error is %s", ct, err)
+		return ""
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	if len(ciphertext)%aes.BlockSize != 0 {
-		a.Err = fmt.Errorf("%s : ciphertext is not a multiple of the block size", ct)
-		log.Error(a.Err.Error())
-		return ct
+	plaintext, err := c.Decrypt(ciphertext)
+	a.Err = err
+	if err != nil {
+		log.Errorf("cbc decrypt error, the error is %s", err)
+		return ""
 	}
+	return string(plaintext)
+}
 
-	mode := cipher.NewCBCDecrypter(block, iv)
-
-	mode.CryptBlocks(ciphertext, ciphertext)
-	ciphertext = pkcs7UnPadding(ciphertext)
-	if ciphertext == nil {
-		a.Err = fmt.Errorf("the ciphertext is nil")
-		log.Error(a.Err.Error())
-		return ct
+// cipher 构造时密钥解码失败则始终返回该错误，避免使用截断的密钥加密
+func (a *AESCBCMode) cipher() (*AESCBC, error) {
+	if a.keyErr != nil {
+		return nil, a.keyErr
 	}
-	return string(ciphertext)
+	return NewAESCBC(a.Key)
 }
 
 func pkcs7Padding(ciphertext []byte, blockSize int) []byte {
-	padding := blockSize - len(ciphertext)%blockSize
-	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
-	return append(ciphertext, padtext...)
+	return PKCS7Pad(ciphertext, blockSize)
 }
 
+// pkcs7UnPadding 填充不合法时返回nil
 func pkcs7UnPadding(origData []byte) []byte {
-	length := len(origData)
-	if length == 0 {
-		log.Error("the length of the origData is empty")
+	data, err := PKCS7Unpad(origData, 0)
+	if err != nil {
+		log.Errorf("pkcs7 unpadding error: %s", err)
 		return nil
 	}
-
-	unpadding := int(origData[length-1])
-	return origData[:(length - unpadding)]
+	return data
 }
 
-// AESCFBMode CFB 模式的 AES 加密
+// AESCFBMode CFB 模式的 AES 加密，需要错误返回值时使用 AESCFB
 type AESCFBMode struct {
 	Key []byte
 	Err error
@@ -193,111 +150,85 @@ func NewAESCFBEncrypt(sKey string) *AESCFBMode {
 	}
 }
 
-// Encrypt aesCFBEncrypt aes 加密  对商户敏感信息加密
+// Encrypt aesCFBEncrypt aes 加密  对商户敏感信息加密，失败时返回空字符串
 func (a *AESCFBMode) Encrypt(pt string) string {
-
-	block, err := aes.NewCipher(a.Key)
+	c, err := NewAESCFB(a.Key)
 	if err != nil {
 		a.Err = err
 		log.Error("create the block error")
 		return ""
 	}
-	plaintext := []byte(pt)
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		a.Err = err
+	ciphertext, err := c.Encrypt([]byte(pt))
+	a.Err = err
+	if err != nil {
 		log.Error("generate the rand num error")
 		return ""
 	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	//stream := cipher.NewCFBEncrypter(block, []byte("0000000000000000"))
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
-
 	return hex.EncodeToString(ciphertext)
 }
 
-// Decrypt aes 解密
+// Decrypt aes 解密，失败时返回空字符串
 func (a *AESCFBMode) Decrypt(ct string) string {
-
-	block, err := aes.NewCipher(a.Key)
+	c, err := NewAESCFB(a.Key)
 	if err != nil {
 		a.Err = err
 		log.Error("create the block error")
 		return ""
 	}
-
 	ciphertext, err := hex.DecodeString(ct)
 	if err != nil {
 		a.Err = err
 		log.Errorf("Decode string error, the string is %s, error is %s", ct, err.Error())
 		return ""
 	}
-
-	if len(ciphertext) < aes.BlockSize {
-		err = fmt.Errorf("the length of the ciphertext is too short")
-		a.Err = err
+	plaintext, err := c.Decrypt(ciphertext)
+	a.Err = err
+	if err != nil {
 		log.Errorf("%s", err)
 		return ""
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
-	return string(ciphertext)
+	return string(plaintext)
 }
 
-// AESEncryptECBStr ECB加密
+// AESEncryptECBStr ECB加密，密钥折叠为16字节，失败时返回空字符串
 func AESEncryptECBStr(source string, keys string) string {
 	if source == "" {
 		return ""
 	}
-	// 字符串转换成切片
-	src := []byte(source)
-	key := []byte(keys)
-	cipher, _ := aes.NewCipher(generateKeys(key))
-	length := (len(src) + aes.BlockSize) / aes.BlockSize
-	plain := make([]byte, length*aes.BlockSize)
-	copy(plain, src)
-	pad := byte(len(plain) - len(src))
-	for i := len(src); i < len(plain); i++ {
-		plain[i] = pad
+	c, err := NewAESECB(generateKeys([]byte(keys)))
+	if err != nil {
+		log.Errorf("create the block error, the error is %s", err)
+		return ""
 	}
-	encrypted := make([]byte, len(plain))
-	// 分组分块加密
-	for bs, be := 0, cipher.BlockSize(); bs <= len(src); bs, be = bs+cipher.BlockSize(), be+cipher.BlockSize() {
-		cipher.Encrypt(encrypted[bs:be], plain[bs:be])
+	encrypted, err := c.Encrypt([]byte(source))
+	if err != nil {
+		log.Errorf("ecb encrypt error, the error is %s", err)
+		return ""
 	}
-
-	//return encrypted
-	encryptstr := strings.ToUpper(hex.EncodeToString(encrypted))
-	return encryptstr
+	return strings.ToUpper(hex.EncodeToString(encrypted))
 }
 
-// AESDecryptECBStr ECB解密
+// AESDecryptECBStr ECB解密，失败时返回空字符串
 func AESDecryptECBStr(encrypteds string, keys string) string {
 	if encrypteds == "" {
 		return ""
 	}
-	// 字符串转换成切片
-	//encrypted := []byte(encrypteds)
-	encrypted, _ := hex.DecodeString(encrypteds)
-	key := []byte(keys)
-
-	cipher, _ := aes.NewCipher(generateKeys(key))
-	decrypted := make([]byte, len(encrypted))
-	//
-	for bs, be := 0, cipher.BlockSize(); bs < len(encrypted); bs, be = bs+cipher.BlockSize(), be+cipher.BlockSize() {
-		cipher.Decrypt(decrypted[bs:be], encrypted[bs:be])
+	encrypted, err := hex.DecodeString(encrypteds)
+	if err != nil {
+		log.Errorf("Decode string error, the string is %s, error is %s", encrypteds, err)
+		return ""
 	}
-
-	trim := 0
-	if len(decrypted) > 0 {
-		trim = len(decrypted) - int(decrypted[len(decrypted)-1])
+	c, err := NewAESECB(generateKeys([]byte(keys)))
+	if err != nil {
+		log.Errorf("create the block error, the error is %s", err)
+		return ""
+	}
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil {
+		log.Errorf("ecb decrypt error, the error is %s", err)
+		return ""
 	}
-	return string(decrypted[:trim])
+	return string(decrypted)
 }
 
 func generateKeys(key []byte) (genKey []byte) {
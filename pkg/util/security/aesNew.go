@@ -1,65 +1,46 @@
 package security
 
 import (
-	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
 )
 
 // 对明文进行填充
 func Padding(plainText []byte, blockSize int) []byte {
-	//计算要填充的长度
-	n := blockSize - len(plainText)%blockSize
-	//对原来的明文填充n个n
-	temp := bytes.Repeat([]byte{byte(n)}, n)
-	plainText = append(plainText, temp...)
-	return plainText
+	return PKCS7Pad(plainText, blockSize)
 }
 
-// 对密文删除填充
+// 对密文删除填充，填充不合法时返回nil
 func UnPadding(cipherText []byte) []byte {
-	//取出密文最后一个字节end
-	end := cipherText[len(cipherText)-1]
-	//删除填充
-	cipherText = cipherText[:len(cipherText)-int(end)]
-	return cipherText
+	plainText, err := PKCS7Unpad(cipherText, 0)
+	if err != nil {
+		log.Errorf("unpadding error: %s", err)
+		return nil
+	}
+	return plainText
 }
 
-// AEC加密（CBC模式）
+// AEC加密（CBC模式） 这里因为java的aes加密是直接用的key做为VI向量 且长度必须是16位 所以直接用了key，失败时返回nil
 func AES_CBC_Encrypt(plainText []byte, key []byte) []byte {
-	//指定加密算法，返回一个AES算法的Block接口对象
-	block, err := aes.NewCipher(key)
+	c, err := NewAESCBCStaticIV(key, key)
 	if err != nil {
-		panic(err)
+		log.Errorf("aes cbc encrypt error: %s", err)
+		return nil
 	}
-	//进行填充
-	plainText = Padding(plainText, block.BlockSize())
-	//指定初始向量vi,长度和block的块尺寸一致
-	//iv:=[]byte("12345678abcdefgh")
-	//指定分组模式，返回一个BlockMode接口对象  这里因为java的aes加密是直接用的key做为VI向量 且长度必须是16位 所以直接用了key
-	blockMode := cipher.NewCBCEncrypter(block, key)
-	//加密连续数据库
-	cipherText := make([]byte, len(plainText))
-	blockMode.CryptBlocks(cipherText, plainText)
-	//返回密文
+	cipherText, _ := c.Encrypt(plainText)
 	return cipherText
 }
 
-// AEC解密（CBC模式） 这里用的时候要特别注意VI向量
+// AEC解密（CBC模式） 这里用的时候要特别注意VI向量，失败时返回nil
 func AES_CBC_Decrypt(cipherText []byte, key []byte) []byte {
-	//指定解密算法，返回一个AES算法的Block接口对象
-	block, err := aes.NewCipher(key)
+	c, err := NewAESCBCStaticIV(key, []byte("12345678abcdefgh"))
+	if err != nil {
+		log.Errorf("aes cbc decrypt error: %s", err)
+		return nil
+	}
+	plainText, err := c.Decrypt(cipherText)
 	if err != nil {
-		panic(err)
+		log.Errorf("aes cbc decrypt error: %s", err)
+		return nil
 	}
-	//指定初始化向量IV,和加密的一致
-	iv := []byte("12345678abcdefgh")
-	//指定分组模式，返回一个BlockMode接口对象
-	blockMode := cipher.NewCBCDecrypter(block, iv)
-	//解密
-	plainText := make([]byte, len(cipherText))
-	blockMode.CryptBlocks(plainText, cipherText)
-	//删除填充
-	plainText = UnPadding(plainText)
 	return plainText
 }
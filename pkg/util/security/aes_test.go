@@ -0,0 +1,232 @@
+package security
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestAESCBCModeRoundTrip(t *testing.T) {
+	a := NewAESCBCEncrypt(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")), "fedcba9876543210")
+	ct := a.Encrypt("hello")
+	if a.Err != nil || ct == "" {
+		t.Fatalf("Encrypt = %q, %v", ct, a.Err)
+	}
+	if pt := a.Decrypt(ct); a.Err != nil || pt != "hello" {
+		t.Fatalf("Decrypt = %q, %v", pt, a.Err)
+	}
+	if pt := a.Decrypt(base64.StdEncoding.EncodeToString(make([]byte, 32))); pt != "" || a.Err == nil {
+		t.Errorf("Decrypt of garbage = %q, %v, want error", pt, a.Err)
+	}
+}
+
+func TestAESCBCModeKeyErrorIsSticky(t *testing.T) {
+	// base64 解码在非法字符前已得到16字节，不能用这部分密钥加密
+	b64Key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")) + "!!!!"
+	a := NewAESCBCEncrypt(b64Key, "fedcba9876543210")
+	if a.Err == nil {
+		t.Fatal("NewAESCBCEncrypt accepted an invalid base64 key")
+	}
+	for i := 0; i < 2; i++ {
+		if ct := a.Encrypt("hello"); ct != "" || a.Err == nil {
+			t.Fatalf("Encrypt call %d = %q, %v, want error", i, ct, a.Err)
+		}
+	}
+	if pt := a.Decrypt("AAAA"); pt != "" || a.Err == nil {
+		t.Errorf("Decrypt = %q, %v, want error", pt, a.Err)
+	}
+}
+
+func TestPKCS7Unpad(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		blockSize int
+		want      []byte
+		wantErr   bool
+	}{
+		{"full block of padding", bytes.Repeat([]byte{16}, 16), 16, []byte{}, false},
+		{"one byte padding", append([]byte("0123456789abcde"), 1), 16, []byte("0123456789abcde"), false},
+		{"unchecked block size", append([]byte("abc"), 2, 2), 0, []byte("abc"), false},
+		{"empty", nil, 16, nil, true},
+		{"not a multiple of block size", append([]byte("0123456789abcd"), 1), 16, nil, true},
+		{"zero padding byte", append([]byte("0123456789abcde"), 0), 16, nil, true},
+		{"padding larger than block", append(make([]byte, 31), 17), 16, nil, true},
+		{"padding larger than data", []byte{5, 5, 5}, 0, nil, true},
+		{"inconsistent padding bytes", append([]byte("0123456789ab"), 1, 4, 4, 4), 16, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PKCS7Unpad(tt.data, tt.blockSize)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPadding) || got != nil {
+					t.Errorf("PKCS7Unpad = %v, %v, want ErrInvalidPadding", got, err)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("PKCS7Unpad = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	for n := 0; n <= 32; n++ {
+		data := bytes.Repeat([]byte("x"), n)
+		padded := PKCS7Pad(data, 16)
+		if len(padded)%16 != 0 || len(padded) <= n {
+			t.Fatalf("PKCS7Pad(%d bytes) = %d bytes", n, len(padded))
+		}
+		if got, err := PKCS7Unpad(padded, 16); err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("unpad of %d bytes = %q, %v", n, got, err)
+		}
+	}
+}
+
+// badPaddingBlock 用原始分组加密构造一个解密后填充不合法的密文块
+func badPaddingBlock(t *testing.T, key []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := append([]byte("0123456789a"), 9, 9, 9, 9, 5)
+	out := make([]byte, len(pt))
+	block.Encrypt(out, pt)
+	return out
+}
+
+func TestCiphers(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	cbc, _ := NewAESCBC(key)
+	cfb, _ := NewAESCFB(key)
+	ecb, _ := NewAESECB(key)
+	static, _ := NewAESCBCStaticIV(key, []byte("12345678abcdefgh"))
+	desc, _ := NewDESCBC([]byte("8bytekey"))
+
+	tests := []struct {
+		name    string
+		c       Cipher
+		invalid map[string][]byte
+	}{
+		{"AESCBC", cbc, map[string][]byte{
+			"short":           make([]byte, 8),
+			"iv only":         make([]byte, 16),
+			"not full blocks": make([]byte, 16+15),
+		}},
+		{"AESCFB", cfb, map[string][]byte{"short": make([]byte, 15)}},
+		{"AESECB", ecb, map[string][]byte{
+			"empty":           nil,
+			"not full blocks": make([]byte, 17),
+			"bad padding":     badPaddingBlock(t, key),
+		}},
+		{"AESCBCStaticIV", static, map[string][]byte{
+			"empty":           nil,
+			"not full blocks": make([]byte, 15),
+		}},
+		{"DESCBC", desc, map[string][]byte{
+			"empty":           nil,
+			"not full blocks": make([]byte, 7),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, n := range []int{0, 1, 15, 16, 17, 100} {
+				pt := bytes.Repeat([]byte("p"), n)
+				ct, err := tt.c.Encrypt(pt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := tt.c.Decrypt(ct)
+				if err != nil || !bytes.Equal(got, pt) {
+					t.Fatalf("%d bytes: Decrypt = %q, %v", n, got, err)
+				}
+			}
+			for name, ct := range tt.invalid {
+				if pt, err := tt.c.Decrypt(ct); err == nil {
+					t.Errorf("%s: Decrypt = %q, want error", name, pt)
+				}
+			}
+		})
+	}
+
+	if _, err := NewAESCBC([]byte("short")); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("NewAESCBC short key: %v, want ErrInvalidKeySize", err)
+	}
+	if _, err := NewAESCBCStaticIV(key, []byte("short")); !errors.Is(err, ErrInvalidIVLength) {
+		t.Errorf("NewAESCBCStaticIV short iv: %v, want ErrInvalidIVLength", err)
+	}
+	if _, err := NewDESCBC([]byte("short")); err == nil {
+		t.Error("NewDESCBC accepted a 5 byte key")
+	}
+	if _, err := ecb.Decrypt(badPaddingBlock(t, key)); !errors.Is(err, ErrInvalidPadding) {
+		t.Errorf("ECB bad padding: %v, want ErrInvalidPadding", err)
+	}
+}
+
+func TestLegacyWrappersDoNotPanic(t *testing.T) {
+	key := []byte("12345678abcdefgh")
+	ct := AES_CBC_Encrypt([]byte("hello"), key)
+	if pt := AES_CBC_Decrypt(ct, key); string(pt) != "hello" {
+		t.Errorf("AES_CBC_Decrypt = %q, want hello", pt)
+	}
+	des, err := DESEncrypt([]byte("hello"), "8bytekey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := DESDecrypt(des, "8bytekey"); err != nil || string(pt) != "hello" {
+		t.Errorf("DESDecrypt = %q, %v", pt, err)
+	}
+	if s := DESDecryptString(DESEncryptString("hello", "8bytekey"), "8bytekey"); s != "hello" {
+		t.Errorf("DESDecryptString = %q, want hello", s)
+	}
+	if s := AESDecryptECBStr(AESEncryptECBStr("hello", "a key longer than sixteen bytes"), "a key longer than sixteen bytes"); s != "hello" {
+		t.Errorf("AESDecryptECBStr = %q, want hello", s)
+	}
+	cfb := NewAESCFBEncrypt("0123456789abcdef")
+	if s := cfb.Decrypt(cfb.Encrypt("hello")); s != "hello" || cfb.Err != nil {
+		t.Errorf("AESCFBMode round trip = %q, %v", s, cfb.Err)
+	}
+
+	// 非法输入返回nil或空字符串，不会panic
+	bytesTests := map[string]func() []byte{
+		"AES_CBC_Encrypt short key":   func() []byte { return AES_CBC_Encrypt([]byte("hello"), []byte("short")) },
+		"AES_CBC_Decrypt short key":   func() []byte { return AES_CBC_Decrypt(ct, []byte("short")) },
+		"AES_CBC_Decrypt partial":     func() []byte { return AES_CBC_Decrypt(ct[:15], key) },
+		"AES_CBC_Decrypt empty":       func() []byte { return AES_CBC_Decrypt(nil, key) },
+		"AES_CBC_Decrypt bad padding": func() []byte { return AES_CBC_Decrypt(badPaddingBlock(t, key), key) },
+		"UnPadding":                   func() []byte { return UnPadding([]byte{1, 2, 3}) },
+		"UnPadding empty":             func() []byte { return UnPadding(nil) },
+		"PKCS5UnPadding":              func() []byte { return PKCS5UnPadding([]byte{0}) },
+	}
+	for name, fn := range bytesTests {
+		if got := fn(); got != nil {
+			t.Errorf("%s = %v, want nil", name, got)
+		}
+	}
+	if _, err := DESEncrypt([]byte("hello"), "short"); err == nil {
+		t.Error("DESEncrypt accepted a 5 byte key")
+	}
+	if _, err := DESDecrypt(des[:7], "8bytekey"); !errors.Is(err, ErrNotFullBlocks) {
+		t.Errorf("DESDecrypt partial block: %v, want ErrNotFullBlocks", err)
+	}
+	stringTests := map[string]func() string{
+		"DESEncryptString short key":  func() string { return DESEncryptString("hello", "short") },
+		"DESDecryptString bad base64": func() string { return DESDecryptString("!!", "8bytekey") },
+		"DESDecryptString partial":    func() string { return DESDecryptString("AAAA", "8bytekey") },
+		"AESDecryptECBStr bad hex":    func() string { return AESDecryptECBStr("zz", "key") },
+		"AESDecryptECBStr partial":    func() string { return AESDecryptECBStr("00ff", "key") },
+		"AESCFBMode short key":        func() string { return NewAESCFBEncrypt("short").Encrypt("hello") },
+		"AESCFBMode bad hex":          func() string { return cfb.Decrypt("zz") },
+		"AESCFBMode short ciphertext": func() string { return cfb.Decrypt("00ff") },
+		"AESCBCMode bad base64": func() string {
+			return NewAESCBCEncrypt(base64.StdEncoding.EncodeToString(key), "").Decrypt("!!")
+		},
+	}
+	for name, fn := range stringTests {
+		if got := fn(); got != "" {
+			t.Errorf("%s = %q, want empty", name, got)
+		}
+	}
+}
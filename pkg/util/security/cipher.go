@@ -0,0 +1,274 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+)
+
+// Cipher 字节加解密，各模式统一返回错误，不会panic
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// 分组加密错误，可使用 errors.Is 判断
+var (
+	ErrInvalidPadding  = errors.New("security: invalid padding")
+	ErrNotFullBlocks   = errors.New("security: ciphertext is not a multiple of the block size")
+	ErrInvalidIVLength = errors.New("security: IV length must equal block size")
+)
+
+var (
+	_ Cipher = (*AESCBC)(nil)
+	_ Cipher = (*AESCFB)(nil)
+	_ Cipher = (*AESECB)(nil)
+	_ Cipher = (*AESCBCStaticIV)(nil)
+	_ Cipher = (*DESCBC)(nil)
+	_ Cipher = gcmCipher{}
+)
+
+// PKCS7Pad PKCS#7填充，PKCS#5是块大小为8时的特例
+func PKCS7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	for i := len(data); i < len(out); i++ {
+		out[i] = byte(n)
+	}
+	return out
+}
+
+// PKCS7Unpad 校验并去除PKCS#7填充，blockSize 为0时不校验长度是否为块大小的整数倍
+func PKCS7Unpad(data []byte, blockSize int) ([]byte, error) {
+	length := len(data)
+	if length == 0 || (blockSize > 0 && length%blockSize != 0) {
+		return nil, ErrInvalidPadding
+	}
+	n := int(data[length-1])
+	if n == 0 || n > length || (blockSize > 0 && n > blockSize) {
+		return nil, ErrInvalidPadding
+	}
+	var diff byte
+	for _, b := range data[length-n:] {
+		diff |= b ^ byte(n)
+	}
+	if subtle.ConstantTimeByteEq(diff, 0) != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return data[:length-n], nil
+}
+
+// AESCBC AES-CBC，密文格式为 随机IV + 密文，PKCS#7填充
+type AESCBC struct {
+	block cipher.Block
+}
+
+// NewAESCBC 创建AES-CBC，key 长度为16、24或32字节
+func NewAESCBC(key []byte) (*AESCBC, error) {
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESCBC{block: block}, nil
+}
+
+// Encrypt 加密
+func (a *AESCBC) Encrypt(plaintext []byte) ([]byte, error) {
+	iv, err := randomIV(a.block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	return append(iv, cbcEncrypt(a.block, iv, plaintext)...), nil
+}
+
+// Decrypt 解密
+func (a *AESCBC) Decrypt(ciphertext []byte) ([]byte, error) {
+	bs := a.block.BlockSize()
+	if len(ciphertext) < bs {
+		return nil, ErrCiphertextTooShort
+	}
+	return cbcDecrypt(a.block, ciphertext[:bs], ciphertext[bs:])
+}
+
+// AESCFB AES-CFB，密文格式为 随机IV + 密文
+type AESCFB struct {
+	block cipher.Block
+}
+
+// NewAESCFB 创建AES-CFB，key 长度为16、24或32字节
+func NewAESCFB(key []byte) (*AESCFB, error) {
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESCFB{block: block}, nil
+}
+
+// Encrypt 加密
+func (a *AESCFB) Encrypt(plaintext []byte) ([]byte, error) {
+	iv, err := randomIV(a.block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(iv)+len(plaintext))
+	copy(out, iv)
+	cipher.NewCFBEncrypter(a.block, iv).XORKeyStream(out[len(iv):], plaintext)
+	return out, nil
+}
+
+// Decrypt 解密
+func (a *AESCFB) Decrypt(ciphertext []byte) ([]byte, error) {
+	bs := a.block.BlockSize()
+	if len(ciphertext) < bs {
+		return nil, ErrCiphertextTooShort
+	}
+	out := make([]byte, len(ciphertext)-bs)
+	cipher.NewCFBDecrypter(a.block, ciphertext[:bs]).XORKeyStream(out, ciphertext[bs:])
+	return out, nil
+}
+
+// AESECB AES-ECB，PKCS#7填充，相同明文块得到相同密文块，仅用于兼容旧系统
+type AESECB struct {
+	block cipher.Block
+}
+
+// NewAESECB 创建AES-ECB，key 长度为16、24或32字节
+func NewAESECB(key []byte) (*AESECB, error) {
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESECB{block: block}, nil
+}
+
+// Encrypt 加密
+func (a *AESECB) Encrypt(plaintext []byte) ([]byte, error) {
+	bs := a.block.BlockSize()
+	out := PKCS7Pad(plaintext, bs)
+	for i := 0; i < len(out); i += bs {
+		a.block.Encrypt(out[i:i+bs], out[i:i+bs])
+	}
+	return out, nil
+}
+
+// Decrypt 解密
+func (a *AESECB) Decrypt(ciphertext []byte) ([]byte, error) {
+	bs := a.block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%bs != 0 {
+		return nil, ErrNotFullBlocks
+	}
+	out := make([]byte, len(ciphertext))
+	for i := 0; i < len(out); i += bs {
+		a.block.Decrypt(out[i:i+bs], ciphertext[i:i+bs])
+	}
+	return PKCS7Unpad(out, bs)
+}
+
+// AESCBCStaticIV 固定IV的AES-CBC，密文不含IV，仅用于兼容 AES_CBC_Encrypt 等旧接口
+type AESCBCStaticIV struct {
+	block cipher.Block
+	iv    []byte
+}
+
+// NewAESCBCStaticIV 创建固定IV的AES-CBC，iv 长度必须为16字节
+func NewAESCBCStaticIV(key, iv []byte) (*AESCBCStaticIV, error) {
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, ErrInvalidIVLength
+	}
+	return &AESCBCStaticIV{block: block, iv: append([]byte(nil), iv...)}, nil
+}
+
+// Encrypt 加密
+func (a *AESCBCStaticIV) Encrypt(plaintext []byte) ([]byte, error) {
+	return cbcEncrypt(a.block, a.iv, plaintext), nil
+}
+
+// Decrypt 解密
+func (a *AESCBCStaticIV) Decrypt(ciphertext []byte) ([]byte, error) {
+	return cbcDecrypt(a.block, a.iv, ciphertext)
+}
+
+// DESCBC DES-CBC，IV与密钥相同，密文不含IV，仅用于兼容 DESEncrypt 等旧接口
+type DESCBC struct {
+	block cipher.Block
+	iv    []byte
+}
+
+// NewDESCBC 创建DES-CBC，key 必须为8字节
+func NewDESCBC(key []byte) (*DESCBC, error) {
+	block, err := des.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &DESCBC{block: block, iv: append([]byte(nil), key...)}, nil
+}
+
+// Encrypt 加密
+func (d *DESCBC) Encrypt(plaintext []byte) ([]byte, error) {
+	return cbcEncrypt(d.block, d.iv, plaintext), nil
+}
+
+// Decrypt 解密
+func (d *DESCBC) Decrypt(ciphertext []byte) ([]byte, error) {
+	return cbcDecrypt(d.block, d.iv, ciphertext)
+}
+
+// WithAAD 返回使用固定关联数据的 Cipher
+func (g *AESGCM) WithAAD(aad []byte) Cipher {
+	return gcmCipher{gcm: g, aad: aad}
+}
+
+type gcmCipher struct {
+	gcm *AESGCM
+	aad []byte
+}
+
+func (c gcmCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	return c.gcm.Encrypt(plaintext, c.aad)
+}
+
+func (c gcmCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	return c.gcm.Decrypt(ciphertext, c.aad)
+}
+
+func newAESBlock(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKeySize
+	}
+	return aes.NewCipher(key)
+}
+
+func randomIV(size int) ([]byte, error) {
+	iv := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	return iv, nil
+}
+
+func cbcEncrypt(block cipher.Block, iv, plaintext []byte) []byte {
+	out := PKCS7Pad(plaintext, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+	return out
+}
+
+func cbcDecrypt(block cipher.Block, iv, ciphertext []byte) ([]byte, error) {
+	bs := block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%bs != 0 {
+		return nil, ErrNotFullBlocks
+	}
+	out := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, ciphertext)
+	return PKCS7Unpad(out, bs)
+}
@@ -2,8 +2,6 @@ package security
 
 import (
 	"bytes"
-	"crypto/cipher"
-	"crypto/des"
 	"encoding/base64"
 )
 
@@ -29,33 +27,20 @@ func DESDecryptString(src, encryptKey string) string {
 
 // DESEncrypt DES 加密  DES CBC模式中的iv 即为密码key key必须是8位
 func DESEncrypt(origData []byte, key string) ([]byte, error) {
-	block, err := des.NewCipher([]byte(key))
+	c, err := NewDESCBC([]byte(key))
 	if err != nil {
 		return nil, err
 	}
-	origData = PKCS5Padding(origData, block.BlockSize())
-	// origData = ZeroPadding(origData, block.BlockSize())
-	blockMode := cipher.NewCBCEncrypter(block, []byte(key))
-	crypted := make([]byte, len(origData))
-	// 根据CryptBlocks方法的说明，如下方式初始化crypted也可以
-	// crypted := origData
-	blockMode.CryptBlocks(crypted, origData)
-	return crypted, nil
+	return c.Encrypt(origData)
 }
 
-// DESDecrypt DES 解密
+// DESDecrypt DES 解密，填充不合法时返回 ErrInvalidPadding
 func DESDecrypt(crypted []byte, key string) ([]byte, error) {
-	block, err := des.NewCipher([]byte(key))
+	c, err := NewDESCBC([]byte(key))
 	if err != nil {
 		return nil, err
 	}
-	blockMode := cipher.NewCBCDecrypter(block, []byte(key))
-	origData := make([]byte, len(crypted))
-	// origData := crypted
-	blockMode.CryptBlocks(origData, crypted)
-	origData = PKCS5UnPadding(origData)
-	// origData = ZeroUnPadding(origData)
-	return origData, nil
+	return c.Decrypt(crypted)
 }
 
 // ZeroPadding ZeroPadding
@@ -74,15 +59,14 @@ func ZeroUnPadding(origData []byte) []byte {
 
 // PKCS5Padding PKCS5Padding
 func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
-	padding := blockSize - len(ciphertext)%blockSize
-	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
-	return append(ciphertext, padtext...)
+	return PKCS7Pad(ciphertext, blockSize)
 }
 
-// PKCS5UnPadding PKCS5UnPadding
+// PKCS5UnPadding PKCS5UnPadding，填充不合法时返回nil
 func PKCS5UnPadding(origData []byte) []byte {
-	length := len(origData)
-	// 去掉最后一个字节 unpadding 次
-	unpadding := int(origData[length-1])
-	return origData[:(length - unpadding)]
+	data, err := PKCS7Unpad(origData, 0)
+	if err != nil {
+		return nil
+	}
+	return data
 }
@@ -0,0 +1,174 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// 信封加密格式：
+//
+//	magic "PKGE"(4) + 版本(1) + 主密钥ID长度(1) + 主密钥ID + 数据密钥密文长度(2, 大端) + 数据密钥密文 + 数据密文
+//
+// 数据密文为 AESGCM 格式，每条记录使用独立的随机数据密钥，主密钥只用于加密数据密钥。
+const (
+	envelopeMagic         = "PKGE"
+	envelopeVersion1 byte = 1
+)
+
+// ErrInvalidEnvelope 信封密文格式错误
+var ErrInvalidEnvelope = errors.New("security: invalid envelope ciphertext")
+
+// Envelope 信封加密，密文中带主密钥ID，解密时按ID查找主密钥，主密钥轮换后旧数据仍可解密
+type Envelope struct {
+	provider KeyProvider
+}
+
+// NewEnvelope 创建信封加密
+func NewEnvelope(provider KeyProvider) *Envelope {
+	return &Envelope{provider: provider}
+}
+
+// Encrypt 使用当前主密钥加密，aad 为可选的关联数据
+func (e *Envelope) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	keyID, err := e.provider.CurrentKeyID()
+	if err != nil {
+		return nil, err
+	}
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := e.wrapKey(keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewAESGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := g.Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEnvelope(keyID, wrapped, payload), nil
+}
+
+// Decrypt 按密文中的主密钥ID解密
+func (e *Envelope) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	keyID, wrapped, payload, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := e.provider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewAESGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return g.Decrypt(payload, aad)
+}
+
+// Rewrap 使用当前主密钥重新加密数据密钥，不需要重新加密数据，已是当前主密钥时原样返回
+func (e *Envelope) Rewrap(ciphertext []byte) ([]byte, error) {
+	keyID, wrapped, payload, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	current, err := e.provider.CurrentKeyID()
+	if err != nil {
+		return nil, err
+	}
+	if keyID == current {
+		return ciphertext, nil
+	}
+	dataKey, err := e.provider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped, err = e.wrapKey(current, dataKey); err != nil {
+		return nil, err
+	}
+	return marshalEnvelope(current, wrapped, payload), nil
+}
+
+// EncryptBase64 加密并返回标准base64编码的密文
+func (e *Envelope) EncryptBase64(plaintext, aad []byte) (string, error) {
+	ct, err := e.Encrypt(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ct), nil
+}
+
+// DecryptBase64 解密标准base64编码的密文
+func (e *Envelope) DecryptBase64(ct string, aad []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(ct)
+	if err != nil {
+		return nil, err
+	}
+	return e.Decrypt(raw, aad)
+}
+
+// EnvelopeKeyID 返回信封密文使用的主密钥ID，可用于统计需要轮换的数据
+func EnvelopeKeyID(ciphertext []byte) (string, error) {
+	keyID, _, _, err := parseEnvelope(ciphertext)
+	return keyID, err
+}
+
+// IsEnvelope 是否为信封加密格式的密文
+func IsEnvelope(ciphertext []byte) bool {
+	_, _, _, err := parseEnvelope(ciphertext)
+	return err == nil
+}
+
+// wrapKey 加密数据密钥并检查长度能否写入信封头
+func (e *Envelope) wrapKey(keyID string, dataKey []byte) ([]byte, error) {
+	if len(keyID) == 0 || len(keyID) > 0xFF {
+		return nil, ErrInvalidEnvelope
+	}
+	wrapped, err := e.provider.WrapKey(keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > 0xFFFF {
+		return nil, ErrInvalidEnvelope
+	}
+	return wrapped, nil
+}
+
+func marshalEnvelope(keyID string, wrapped, payload []byte) []byte {
+	out := make([]byte, 0, len(envelopeMagic)+4+len(keyID)+len(wrapped)+len(payload))
+	out = append(out, envelopeMagic...)
+	out = append(out, envelopeVersion1, byte(len(keyID)))
+	out = append(out, keyID...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, payload...)
+}
+
+func parseEnvelope(b []byte) (keyID string, wrapped, payload []byte, err error) {
+	if len(b) < len(envelopeMagic)+2 || string(b[:len(envelopeMagic)]) != envelopeMagic {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+	b = b[len(envelopeMagic):]
+	if b[0] != envelopeVersion1 {
+		return "", nil, nil, ErrUnsupportedVersion
+	}
+	n := int(b[1])
+	b = b[2:]
+	if n == 0 || len(b) < n+2 {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+	keyID, b = string(b[:n]), b[n:]
+	m := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < m {
+		return "", nil, nil, ErrInvalidEnvelope
+	}
+	return keyID, b[:m], b[m:], nil
+}
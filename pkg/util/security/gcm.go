@@ -0,0 +1,143 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// GCMVersion1 密文格式版本：版本号(1字节) + nonce(12字节) + 密文 + tag(16字节)
+const GCMVersion1 byte = 1
+
+// AES-GCM 错误，可使用 errors.Is 判断
+var (
+	ErrInvalidKeySize     = errors.New("security: AES key must be 16, 24 or 32 bytes")
+	ErrCiphertextTooShort = errors.New("security: ciphertext is too short")
+	ErrUnsupportedVersion = errors.New("security: unsupported ciphertext version")
+	ErrAuthFailed         = errors.New("security: message authentication failed")
+)
+
+// AESGCM AES-GCM 认证加密，每次加密使用随机nonce，并发安全
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM 创建AES-GCM加密对象，key 长度为16、24或32字节
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+// NewAESGCMFromBase64 使用base64编码的密钥创建AES-GCM加密对象
+func NewAESGCMFromBase64(b64Key string) (*AESGCM, error) {
+	key, err := base64.StdEncoding.DecodeString(b64Key)
+	if err != nil {
+		return nil, err
+	}
+	return NewAESGCM(key)
+}
+
+// Encrypt 加密，aad 为可选的关联数据，解密时必须提供相同的值，版本号也参与认证
+func (g *AESGCM) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	nonceSize := g.aead.NonceSize()
+	out := make([]byte, 1+nonceSize, 1+nonceSize+len(plaintext)+g.aead.Overhead())
+	out[0] = GCMVersion1
+	if _, err := io.ReadFull(rand.Reader, out[1:]); err != nil {
+		return nil, err
+	}
+	return g.aead.Seal(out, out[1:], plaintext, gcmAAD(GCMVersion1, aad)), nil
+}
+
+// Decrypt 解密并校验，密文被篡改或 aad 不一致时返回 ErrAuthFailed
+func (g *AESGCM) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	nonceSize := g.aead.NonceSize()
+	if len(ciphertext) < 1+nonceSize+g.aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	if ciphertext[0] != GCMVersion1 {
+		return nil, ErrUnsupportedVersion
+	}
+	nonce := ciphertext[1 : 1+nonceSize]
+	plaintext, err := g.aead.Open(nil, nonce, ciphertext[1+nonceSize:], gcmAAD(ciphertext[0], aad))
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// EncryptBase64 加密并返回标准base64编码的密文
+func (g *AESGCM) EncryptBase64(plaintext, aad []byte) (string, error) {
+	ct, err := g.Encrypt(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ct), nil
+}
+
+// DecryptBase64 解密标准base64编码的密文
+func (g *AESGCM) DecryptBase64(ct string, aad []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(ct)
+	if err != nil {
+		return nil, err
+	}
+	return g.Decrypt(raw, aad)
+}
+
+// EncryptHex 加密并返回hex编码的密文
+func (g *AESGCM) EncryptHex(plaintext, aad []byte) (string, error) {
+	ct, err := g.Encrypt(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(ct), nil
+}
+
+// DecryptHex 解密hex编码的密文
+func (g *AESGCM) DecryptHex(ct string, aad []byte) ([]byte, error) {
+	raw, err := hex.DecodeString(ct)
+	if err != nil {
+		return nil, err
+	}
+	return g.Decrypt(raw, aad)
+}
+
+// AESGCMEncrypt 使用 key 进行AES-GCM加密
+func AESGCMEncrypt(plaintext, key, aad []byte) ([]byte, error) {
+	g, err := NewAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return g.Encrypt(plaintext, aad)
+}
+
+// AESGCMDecrypt 使用 key 进行AES-GCM解密
+func AESGCMDecrypt(ciphertext, key, aad []byte) ([]byte, error) {
+	g, err := NewAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return g.Decrypt(ciphertext, aad)
+}
+
+// gcmAAD 版本号作为关联数据的第一个字节，防止修改版本号
+func gcmAAD(version byte, aad []byte) []byte {
+	out := make([]byte, 0, 1+len(aad))
+	out = append(out, version)
+	return append(out, aad...)
+}
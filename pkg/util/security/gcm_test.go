@@ -0,0 +1,118 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testGCMKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	for _, n := range []int{16, 24, 32} {
+		g, err := NewAESGCM(testGCMKey[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pt := range [][]byte{nil, []byte("hello"), bytes.Repeat([]byte("x"), 1000)} {
+			ct, err := g.Encrypt(pt, []byte("user:1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct[0] != GCMVersion1 || len(ct) != 1+12+len(pt)+16 {
+				t.Fatalf("key %d: ciphertext version %d, length %d", n, ct[0], len(ct))
+			}
+			got, err := g.Decrypt(ct, []byte("user:1"))
+			if err != nil || !bytes.Equal(got, pt) {
+				t.Fatalf("key %d: Decrypt = %q, %v", n, got, err)
+			}
+		}
+	}
+
+	// 随机nonce，相同明文两次加密结果不同
+	g, _ := NewAESGCM(testGCMKey)
+	a, _ := g.Encrypt([]byte("hello"), nil)
+	b, _ := g.Encrypt([]byte("hello"), nil)
+	if bytes.Equal(a, b) {
+		t.Error("two encryptions of the same plaintext are identical")
+	}
+}
+
+func TestAESGCMEncodings(t *testing.T) {
+	g, _ := NewAESGCM(testGCMKey)
+	b64, err := g.EncryptBase64([]byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := g.DecryptBase64(b64, nil); err != nil || string(pt) != "hello" {
+		t.Errorf("DecryptBase64 = %q, %v", pt, err)
+	}
+	h, err := g.EncryptHex([]byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := g.DecryptHex(h, nil); err != nil || string(pt) != "hello" {
+		t.Errorf("DecryptHex = %q, %v", pt, err)
+	}
+	ct, err := AESGCMEncrypt([]byte("hello"), testGCMKey, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := AESGCMDecrypt(ct, testGCMKey, []byte("aad")); err != nil || string(pt) != "hello" {
+		t.Errorf("AESGCMDecrypt = %q, %v", pt, err)
+	}
+}
+
+func TestAESGCMRejects(t *testing.T) {
+	g, _ := NewAESGCM(testGCMKey)
+	aad := []byte("user:1")
+	ct, err := g.Encrypt([]byte("hello"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(i int) []byte {
+		out := append([]byte(nil), ct...)
+		out[i] ^= 1
+		return out
+	}
+	other, _ := NewAESGCM([]byte("fedcba9876543210fedcba9876543210"))
+
+	tests := []struct {
+		name string
+		g    *AESGCM
+		ct   []byte
+		aad  []byte
+		want error
+	}{
+		{"unknown version", g, append([]byte{2}, ct[1:]...), aad, ErrUnsupportedVersion},
+		{"version 0", g, append([]byte{0}, ct[1:]...), aad, ErrUnsupportedVersion},
+		{"aad mismatch", g, ct, []byte("user:2"), ErrAuthFailed},
+		{"missing aad", g, ct, nil, ErrAuthFailed},
+		{"tampered nonce", g, flip(1), aad, ErrAuthFailed},
+		{"tampered ciphertext", g, flip(13), aad, ErrAuthFailed},
+		{"tampered tag", g, flip(len(ct) - 1), aad, ErrAuthFailed},
+		{"wrong key", other, ct, aad, ErrAuthFailed},
+		{"truncated tag", g, ct[:len(ct)-1], aad, ErrAuthFailed},
+		{"too short", g, ct[:1+12+15], aad, ErrCiphertextTooShort},
+		{"empty", g, nil, aad, ErrCiphertextTooShort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := tt.g.Decrypt(tt.ct, tt.aad)
+			if !errors.Is(err, tt.want) || pt != nil {
+				t.Errorf("Decrypt = %q, %v, want %v", pt, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAESGCMKeySize(t *testing.T) {
+	for _, n := range []int{0, 8, 15, 33} {
+		if _, err := NewAESGCM(make([]byte, n)); !errors.Is(err, ErrInvalidKeySize) {
+			t.Errorf("key of %d bytes: %v, want ErrInvalidKeySize", n, err)
+		}
+	}
+	if _, err := NewAESGCMFromBase64("!!"); err == nil {
+		t.Error("NewAESGCMFromBase64 accepted invalid base64")
+	}
+}
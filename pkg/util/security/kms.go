@@ -0,0 +1,281 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zhuzhaoman/pkgutil/pkg/log"
+)
+
+// 密钥管理错误，可使用 errors.Is 判断
+var (
+	ErrKeyNotFound  = errors.New("security: key not found")
+	ErrNoCurrentKey = errors.New("security: no current key")
+)
+
+// KeyProvider 主密钥管理，负责加解密数据密钥，主密钥本身不离开实现，可对接云KMS
+type KeyProvider interface {
+	// CurrentKeyID 当前用于加密新数据的主密钥ID
+	CurrentKeyID() (string, error)
+	// WrapKey 使用指定主密钥加密数据密钥
+	WrapKey(keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey 使用指定主密钥解密数据密钥，主密钥不存在时返回 ErrKeyNotFound
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// localKeyFile 本地密钥文件格式
+//
+//	{"current": "k20240101000000-1a2b3c4d", "keys": {"k20240101000000-1a2b3c4d": "<base64 32字节>"}}
+type localKeyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LocalKeyProvider 基于本地JSON文件的主密钥管理，轮换后旧密钥保留用于解密，并发安全，
+// 读写密钥文件时对同目录的 path+".lock" 加锁，多个进程可以共享同一个密钥文件
+type LocalKeyProvider struct {
+	path    string
+	mu      sync.RWMutex
+	current string
+	keys    map[string]*AESGCM
+}
+
+// NewLocalKeyProvider 加载密钥文件，文件不存在时创建并生成第一个主密钥，
+// 多个进程同时创建时只有一个会写入，其余加载已创建的文件
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{path: path}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	file, err := p.load()
+	if errors.Is(err, os.ErrNotExist) {
+		file = localKeyFile{Keys: map[string]string{}}
+		var id string
+		if id, err = addLocalKey(&file); err != nil {
+			return nil, err
+		}
+		file.Current = id
+		if err = p.write(file, true); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.apply(file); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload 重新读取密钥文件，文件权限对其他用户可读时打印警告
+func (p *LocalKeyProvider) Reload() error {
+	unlock, err := lockFile(p.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := p.load()
+	if err != nil {
+		return err
+	}
+	return p.apply(file)
+}
+
+// Rotate 生成新的主密钥并设为当前密钥，旧密钥保留用于解密，返回新密钥ID
+//
+// 加锁后重新读取密钥文件，只在文件内容上加入新密钥，其他进程已轮换出的密钥不会丢失，手动从文件删除的密钥也不会被恢复，
+// 密钥文件不存在时返回错误。
+func (p *LocalKeyProvider) Rotate() (string, error) {
+	unlock, err := lockFile(p.path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	file, err := p.load()
+	if err != nil {
+		return "", err
+	}
+	id, err := addLocalKey(&file)
+	if err != nil {
+		return "", err
+	}
+	file.Current = id
+	if err := p.write(file, false); err != nil {
+		return "", err
+	}
+	return id, p.apply(file)
+}
+
+// load 读取并解析密钥文件，调用方需持有文件锁
+func (p *LocalKeyProvider) load() (localKeyFile, error) {
+	var file localKeyFile
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return file, err
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		log.Warnf("key file %s is accessible by other users, mode %s", p.path, fi.Mode().Perm())
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("security: parse key file %s error: %w", p.path, err)
+	}
+	if file.Keys == nil {
+		file.Keys = map[string]string{}
+	}
+	return file, nil
+}
+
+// apply 校验密钥文件内容并替换内存中的密钥
+func (p *LocalKeyProvider) apply(file localKeyFile) error {
+	keys := make(map[string]*AESGCM, len(file.Keys))
+	for id, b64Key := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(b64Key)
+		if err != nil {
+			return fmt.Errorf("security: decode key %s error: %w", id, err)
+		}
+		g, err := NewAESGCM(key)
+		if err != nil {
+			return fmt.Errorf("security: key %s: %w", id, err)
+		}
+		keys[id] = g
+	}
+	if _, ok := keys[file.Current]; !ok {
+		return fmt.Errorf("%w: current key %q is not in key file", ErrNoCurrentKey, file.Current)
+	}
+
+	p.mu.Lock()
+	p.current, p.keys = file.Current, keys
+	p.mu.Unlock()
+	return nil
+}
+
+// write 写入密钥文件，create 为true时文件已存在则失败，调用方需持有文件锁
+func (p *LocalKeyProvider) write(file localKeyFile, create bool) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if create {
+		return writeFileExclusive(p.path, data, 0o600)
+	}
+	return writeFileAtomic(p.path, data, 0o600)
+}
+
+// addLocalKey 生成新的主密钥加入 file，返回密钥ID
+func addLocalKey(file *localKeyFile) (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	if _, err := io.ReadFull(rand.Reader, suffix); err != nil {
+		return "", err
+	}
+	id := "k" + time.Now().UTC().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
+	file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	return id, nil
+}
+
+// CurrentKeyID 实现 KeyProvider
+func (p *LocalKeyProvider) CurrentKeyID() (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.current == "" {
+		return "", ErrNoCurrentKey
+	}
+	return p.current, nil
+}
+
+// WrapKey 实现 KeyProvider，主密钥ID作为关联数据
+func (p *LocalKeyProvider) WrapKey(keyID string, dataKey []byte) ([]byte, error) {
+	g, err := p.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return g.Encrypt(dataKey, []byte(keyID))
+}
+
+// UnwrapKey 实现 KeyProvider
+func (p *LocalKeyProvider) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	g, err := p.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return g.Decrypt(wrapped, []byte(keyID))
+}
+
+func (p *LocalKeyProvider) key(keyID string) (*AESGCM, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	g, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return g, nil
+}
+
+// writeFileExclusive 以 O_EXCL 创建并写入文件，文件已存在时返回 os.ErrExist
+func writeFileExclusive(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomic 写入同目录临时文件后重命名
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,33 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeout 等待锁文件的最长时间，进程异常退出留下的锁文件需要手动删除
+const lockTimeout = 30 * time.Second
+
+// lockFile 以 O_EXCL 创建 path+".lock" 作为排他锁，解锁时删除
+func lockFile(path string) (unlock func(), err error) {
+	name := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("security: wait for lock file %s timeout", name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
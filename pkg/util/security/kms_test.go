@@ -0,0 +1,176 @@
+package security
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func readLocalKeyFile(t *testing.T, path string) localKeyFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file localKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLocalKeyProviderConcurrentCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	const n = 8
+	providers := make([]*LocalKeyProvider, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			providers[i], errs[i] = NewLocalKeyProvider(path)
+		}(i)
+	}
+	wg.Wait()
+
+	file := readLocalKeyFile(t, path)
+	if len(file.Keys) != 1 {
+		t.Fatalf("key file has %d keys, want 1", len(file.Keys))
+	}
+	for i, p := range providers {
+		if errs[i] != nil {
+			t.Fatalf("provider %d: %v", i, errs[i])
+		}
+		if id, _ := p.CurrentKeyID(); id != file.Current {
+			t.Errorf("provider %d current key = %s, want %s", i, id, file.Current)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, %v, want 0600", fi.Mode().Perm(), err)
+	}
+}
+
+func TestLocalKeyProviderRotateMergesKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	a, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := a.CurrentKeyID()
+
+	// a 使用新密钥加密数据后，b 基于过期的内存状态轮换，a 的密钥不能丢失
+	idA, err := a.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnvelope(a)
+	ct, err := env.Encrypt([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := b.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := readLocalKeyFile(t, path)
+	for _, id := range []string{first, idA, idB} {
+		if _, ok := file.Keys[id]; !ok {
+			t.Errorf("key %s lost after concurrent rotation", id)
+		}
+	}
+	if file.Current != idB {
+		t.Errorf("current key = %s, want %s", file.Current, idB)
+	}
+	pt, err := NewEnvelope(b).Decrypt(ct, nil)
+	if err != nil || string(pt) != "secret" {
+		t.Fatalf("decrypt with rotated provider = %q, %v", pt, err)
+	}
+}
+
+func TestLocalKeyProviderRotateKeepsRemovedKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	p, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, _ := p.CurrentKeyID()
+	kept, err := p.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 运维手动从密钥文件中删除旧密钥，内存中仍保留该密钥
+	file := readLocalKeyFile(t, path)
+	delete(file.Keys, removed)
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := p.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file = readLocalKeyFile(t, path)
+	if _, ok := file.Keys[removed]; ok {
+		t.Errorf("removed key %s restored by Rotate", removed)
+	}
+	for _, want := range []string{kept, id} {
+		if _, ok := file.Keys[want]; !ok {
+			t.Errorf("key %s missing after Rotate", want)
+		}
+	}
+	if _, err := p.key(removed); err == nil {
+		t.Errorf("removed key %s still usable in memory after Rotate", removed)
+	}
+}
+
+func TestLocalKeyProviderConcurrentRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	const n = 6
+	providers := make([]*LocalKeyProvider, n)
+	for i := range providers {
+		p, err := NewLocalKeyProvider(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		providers[i] = p
+	}
+
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p *LocalKeyProvider) {
+			defer wg.Done()
+			id, err := p.Rotate()
+			if err != nil {
+				t.Error(err)
+			}
+			ids[i] = id
+		}(i, p)
+	}
+	wg.Wait()
+
+	file := readLocalKeyFile(t, path)
+	if len(file.Keys) != n+1 {
+		t.Errorf("key file has %d keys, want %d", len(file.Keys), n+1)
+	}
+	for _, id := range ids {
+		if _, ok := file.Keys[id]; !ok {
+			t.Errorf("rotated key %s missing from key file", id)
+		}
+	}
+}
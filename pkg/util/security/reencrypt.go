@@ -0,0 +1,296 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrAmbiguousKey 多个密钥都能解密同一个值，无法确定原密钥
+var ErrAmbiguousKey = errors.New("security: value can be decrypted by more than one key")
+
+// TextEncoding 密文的文本编码，base64.StdEncoding 和 HexEncoding 都实现了此接口
+type TextEncoding interface {
+	EncodeToString(b []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+// HexEncoding hex编码，与 AESCFBMode 的密文格式一致
+var HexEncoding TextEncoding = hexEncoding{}
+
+type hexEncoding struct{}
+
+func (hexEncoding) EncodeToString(b []byte) string {
+	return hex.EncodeToString(b)
+}
+
+func (hexEncoding) DecodeString(s string) ([]byte, error) {
+	return hex.DecodeString(s)
+}
+
+// NamedCipher 带ID的密钥
+type NamedCipher struct {
+	ID     string
+	Cipher Cipher
+}
+
+// Keyring 密钥环，包含重新加密使用的目标密钥和可能加密过旧数据的密钥
+type Keyring struct {
+	target NamedCipher
+	old    []NamedCipher
+	// Validate 校验解密结果是否合理，CBC等无认证模式用错误密钥解密也可能得到合法填充，默认要求为合法UTF-8
+	Validate func(plaintext []byte) bool
+}
+
+// NewKeyring 创建密钥环，target 为新密钥，old 为旧密钥
+func NewKeyring(target NamedCipher, old ...NamedCipher) *Keyring {
+	return &Keyring{target: target, old: old, Validate: utf8.Valid}
+}
+
+// Target 目标密钥ID
+func (k *Keyring) Target() string {
+	return k.target.ID
+}
+
+// Detect 尝试所有密钥解密，返回唯一能解密的密钥ID和明文
+//
+// 没有密钥能解密时返回 ErrKeyNotFound，多个密钥都能解密时返回 ErrAmbiguousKey。
+func (k *Keyring) Detect(ciphertext []byte) (keyID string, plaintext []byte, err error) {
+	found := 0
+	for _, nc := range append([]NamedCipher{k.target}, k.old...) {
+		pt, err := nc.Cipher.Decrypt(ciphertext)
+		if err != nil || (k.Validate != nil && !k.Validate(pt)) {
+			continue
+		}
+		found++
+		if found == 1 {
+			keyID, plaintext = nc.ID, pt
+		}
+	}
+	switch found {
+	case 0:
+		return "", nil, ErrKeyNotFound
+	case 1:
+		return keyID, plaintext, nil
+	}
+	return "", nil, ErrAmbiguousKey
+}
+
+// Record 待重新加密的记录
+type Record struct {
+	ID    string
+	Value string
+}
+
+// RecordIterator 记录迭代器，Next 返回 io.EOF 表示结束
+type RecordIterator interface {
+	Next() (Record, error)
+}
+
+// SliceIterator 遍历内存中的记录
+func SliceIterator(records []Record) RecordIterator {
+	return &sliceIterator{records: records}
+}
+
+type sliceIterator struct {
+	records []Record
+	i       int
+}
+
+func (s *sliceIterator) Next() (Record, error) {
+	if s.i >= len(s.records) {
+		return Record{}, io.EOF
+	}
+	s.i++
+	return s.records[s.i-1], nil
+}
+
+// Progress 重新加密进度
+type Progress struct {
+	Processed   int64
+	ReEncrypted int64
+	Skipped     int64
+	Failed      int64
+	Elapsed     time.Duration
+}
+
+// Failure 重新加密失败的记录
+type Failure struct {
+	ID  string
+	Err error
+}
+
+// ReEncryptReport 重新加密结果
+type ReEncryptReport struct {
+	Progress
+	// ByKey 按原密钥ID统计的记录数
+	ByKey map[string]int64
+	// Failures 失败的记录，最多保存 MaxFailures 条，总数见 Failed
+	Failures []Failure
+}
+
+// ReEncryptConfig 重新加密配置
+type ReEncryptConfig struct {
+	Keyring *Keyring
+	// Encoding 密文的文本编码，默认 base64.StdEncoding，与 AESCBCMode 一致
+	Encoding TextEncoding
+	// Concurrency 并发数，默认4
+	Concurrency int
+	// Save 保存重新加密后的值，会被并发调用，返回错误时记为失败
+	Save func(ctx context.Context, id, value string) error
+	// OnProgress 每处理 ProgressEvery 条记录和结束时回调，结束时的进度已回调过则不再重复，不会并发调用
+	OnProgress func(Progress)
+	// ProgressEvery 默认1000
+	ProgressEvery int64
+	// MaxFailures 报告中保存的失败记录上限，默认1000
+	MaxFailures int
+}
+
+// ReEncryptor 将旧密钥加密的数据批量重新加密为目标密钥
+type ReEncryptor struct {
+	cfg ReEncryptConfig
+}
+
+// NewReEncryptor 创建重新加密器
+func NewReEncryptor(cfg ReEncryptConfig) (*ReEncryptor, error) {
+	if cfg.Keyring == nil {
+		return nil, errors.New("security: keyring is nil")
+	}
+	if cfg.Save == nil {
+		return nil, errors.New("security: save func is nil")
+	}
+	if cfg.Encoding == nil {
+		cfg.Encoding = base64.StdEncoding
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.ProgressEvery <= 0 {
+		cfg.ProgressEvery = 1000
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 1000
+	}
+	return &ReEncryptor{cfg: cfg}, nil
+}
+
+// ReEncryptValue 重新加密单个值，返回原密钥ID，已是目标密钥时 value 原样返回
+func (r *ReEncryptor) ReEncryptValue(value string) (newValue, keyID string, err error) {
+	ct, err := r.cfg.Encoding.DecodeString(value)
+	if err != nil {
+		return "", "", fmt.Errorf("decode value error: %w", err)
+	}
+	keyID, pt, err := r.cfg.Keyring.Detect(ct)
+	if err != nil {
+		return "", "", err
+	}
+	if keyID == r.cfg.Keyring.target.ID {
+		return value, keyID, nil
+	}
+	ct, err = r.cfg.Keyring.target.Cipher.Encrypt(pt)
+	if err != nil {
+		return "", keyID, fmt.Errorf("encrypt with %s error: %w", r.cfg.Keyring.target.ID, err)
+	}
+	return r.cfg.Encoding.EncodeToString(ct), keyID, nil
+}
+
+// Run 遍历 it 中的记录并重新加密，已是目标密钥的记录跳过，单条失败记录到报告中继续处理，
+// 迭代器出错或 ctx 取消时停止并返回已处理部分的报告
+func (r *ReEncryptor) Run(ctx context.Context, it RecordIterator) (*ReEncryptReport, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	report := &ReEncryptReport{ByKey: map[string]int64{}}
+	var (
+		mu       sync.Mutex
+		progress Progress
+		wg       sync.WaitGroup
+		// reported 最近一次回调 OnProgress 时的处理数，避免结束时重复回调相同的进度
+		reported int64 = -1
+	)
+	snapshot := func() Progress {
+		return Progress{
+			Processed:   atomic.LoadInt64(&progress.Processed),
+			ReEncrypted: atomic.LoadInt64(&progress.ReEncrypted),
+			Skipped:     atomic.LoadInt64(&progress.Skipped),
+			Failed:      atomic.LoadInt64(&progress.Failed),
+			Elapsed:     time.Since(start),
+		}
+	}
+	done := func(rec Record, keyID string, skipped bool, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			atomic.AddInt64(&progress.Failed, 1)
+			if len(report.Failures) < r.cfg.MaxFailures {
+				report.Failures = append(report.Failures, Failure{ID: rec.ID, Err: err})
+			}
+		case skipped:
+			atomic.AddInt64(&progress.Skipped, 1)
+		default:
+			atomic.AddInt64(&progress.ReEncrypted, 1)
+		}
+		if keyID != "" {
+			report.ByKey[keyID]++
+		}
+		n := atomic.AddInt64(&progress.Processed, 1)
+		if r.cfg.OnProgress != nil && n%r.cfg.ProgressEvery == 0 {
+			reported = n
+			r.cfg.OnProgress(snapshot())
+		}
+	}
+
+	records := make(chan Record)
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range records {
+				value, keyID, err := r.ReEncryptValue(rec.Value)
+				skipped := err == nil && keyID == r.cfg.Keyring.target.ID
+				if err == nil && !skipped {
+					if err = r.cfg.Save(ctx, rec.ID, value); err != nil {
+						err = fmt.Errorf("save error: %w", err)
+					}
+				}
+				done(rec, keyID, skipped, err)
+			}
+		}()
+	}
+
+	var runErr error
+feed:
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			runErr = fmt.Errorf("security: iterate records error: %w", err)
+			break
+		}
+		select {
+		case records <- rec:
+		case <-ctx.Done():
+			runErr = ctx.Err()
+			break feed
+		}
+	}
+	close(records)
+	wg.Wait()
+
+	report.Progress = snapshot()
+	if r.cfg.OnProgress != nil && report.Processed != reported {
+		r.cfg.OnProgress(report.Progress)
+	}
+	return report, runErr
+}
@@ -0,0 +1,274 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testKeys struct {
+	old, target *AESCBC
+	ring        *Keyring
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	old, err := NewAESCBC([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := NewAESCBC([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{old: old, target: target, ring: NewKeyring(NamedCipher{"new", target}, NamedCipher{"old", old})}
+}
+
+func encryptValue(t *testing.T, c Cipher, pt string) string {
+	t.Helper()
+	ct, err := c.Encrypt([]byte(pt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(ct)
+}
+
+func oldRecords(t *testing.T, c Cipher, n int) []Record {
+	t.Helper()
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{ID: fmt.Sprint(i), Value: encryptValue(t, c, fmt.Sprintf("value-%d", i))}
+	}
+	return records
+}
+
+// memorySink 并发安全的保存结果
+type memorySink struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memorySink) Save(_ context.Context, id, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = map[string]string{}
+	}
+	s.values[id] = value
+	return nil
+}
+
+func TestReEncryptorRunConcurrent(t *testing.T) {
+	keys := newTestKeys(t)
+	records := oldRecords(t, keys.old, 1000)
+	records = append(records,
+		Record{ID: "current", Value: encryptValue(t, keys.target, "already")},
+		Record{ID: "bad-encoding", Value: "!!"},
+		Record{ID: "unknown-key", Value: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+	)
+
+	var (
+		sink  memorySink
+		calls []Progress
+	)
+	r, err := NewReEncryptor(ReEncryptConfig{
+		Keyring:       keys.ring,
+		Concurrency:   8,
+		Save:          sink.Save,
+		OnProgress:    func(p Progress) { calls = append(calls, p) },
+		ProgressEvery: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := r.Run(context.Background(), SliceIterator(records))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Processed != 1003 || report.ReEncrypted != 1000 || report.Skipped != 1 || report.Failed != 2 {
+		t.Fatalf("report = %+v", report.Progress)
+	}
+	if report.ByKey["old"] != 1000 || report.ByKey["new"] != 1 {
+		t.Errorf("ByKey = %v", report.ByKey)
+	}
+	failed := map[string]error{}
+	for _, f := range report.Failures {
+		failed[f.ID] = f.Err
+	}
+	if len(failed) != 2 || !errors.Is(failed["unknown-key"], ErrKeyNotFound) || failed["bad-encoding"] == nil {
+		t.Errorf("failures = %v", report.Failures)
+	}
+	if len(sink.values) != 1000 {
+		t.Fatalf("saved %d values, want 1000", len(sink.values))
+	}
+	for id, value := range sink.values {
+		ct, _ := base64.StdEncoding.DecodeString(value)
+		keyID, pt, err := keys.ring.Detect(ct)
+		if err != nil || keyID != "new" || string(pt) != "value-"+id {
+			t.Fatalf("record %s: key %q, plaintext %q, %v", id, keyID, pt, err)
+		}
+	}
+
+	// 1003 不是100的整数倍，10次中间回调加1次结束回调
+	if len(calls) != 11 {
+		t.Fatalf("OnProgress called %d times, want 11", len(calls))
+	}
+	for i, p := range calls[:10] {
+		if p.Processed != int64(i+1)*100 {
+			t.Errorf("progress %d processed = %d, want %d", i, p.Processed, (i+1)*100)
+		}
+	}
+	if last := calls[10]; last.Processed != 1003 {
+		t.Errorf("final progress processed = %d, want 1003", last.Processed)
+	}
+}
+
+func TestReEncryptorProgressNotDuplicated(t *testing.T) {
+	keys := newTestKeys(t)
+	var calls []int64
+	r, err := NewReEncryptor(ReEncryptConfig{
+		Keyring:       keys.ring,
+		Save:          (&memorySink{}).Save,
+		OnProgress:    func(p Progress) { calls = append(calls, p.Processed) },
+		ProgressEvery: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(context.Background(), SliceIterator(oldRecords(t, keys.old, 30))); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(calls) != "[10 20 30]" {
+		t.Errorf("progress calls = %v, want [10 20 30]", calls)
+	}
+
+	// 没有记录时仍然回调一次结束进度
+	calls = nil
+	if _, err := r.Run(context.Background(), SliceIterator(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(calls) != "[0]" {
+		t.Errorf("progress calls for empty input = %v, want [0]", calls)
+	}
+}
+
+func TestReEncryptorCancel(t *testing.T) {
+	keys := newTestKeys(t)
+	records := oldRecords(t, keys.old, 2000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var saved int32
+	r, err := NewReEncryptor(ReEncryptConfig{
+		Keyring:     keys.ring,
+		Concurrency: 4,
+		Save: func(ctx context.Context, id, value string) error {
+			if atomic.AddInt32(&saved, 1) == 10 {
+				cancel()
+			}
+			return ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := r.Run(ctx, SliceIterator(records))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if report == nil || report.Processed >= int64(len(records)) {
+		t.Fatalf("report after cancel = %+v", report)
+	}
+	if report.Processed != report.ReEncrypted+report.Skipped+report.Failed {
+		t.Errorf("inconsistent report %+v", report.Progress)
+	}
+	if int64(atomic.LoadInt32(&saved)) != report.Processed {
+		t.Errorf("saved %d records, report processed %d", saved, report.Processed)
+	}
+}
+
+// failingIterator 返回 n 条记录后返回错误
+type failingIterator struct {
+	records []Record
+	n       int
+	err     error
+}
+
+func (f *failingIterator) Next() (Record, error) {
+	if f.n == 0 {
+		return Record{}, f.err
+	}
+	f.n--
+	rec := f.records[0]
+	f.records = f.records[1:]
+	return rec, nil
+}
+
+func TestReEncryptorIteratorError(t *testing.T) {
+	keys := newTestKeys(t)
+	iterErr := errors.New("cursor closed")
+	it := &failingIterator{records: oldRecords(t, keys.old, 20), n: 15, err: iterErr}
+
+	var sink memorySink
+	r, err := NewReEncryptor(ReEncryptConfig{Keyring: keys.ring, Concurrency: 3, Save: sink.Save})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := r.Run(context.Background(), it)
+	if !errors.Is(err, iterErr) {
+		t.Fatalf("error = %v, want iterator error", err)
+	}
+	if report.Processed != 15 || report.ReEncrypted != 15 || len(sink.values) != 15 {
+		t.Errorf("report = %+v, saved %d", report.Progress, len(sink.values))
+	}
+}
+
+func TestReEncryptorSaveErrorAndMaxFailures(t *testing.T) {
+	keys := newTestKeys(t)
+	saveErr := errors.New("db unavailable")
+	r, err := NewReEncryptor(ReEncryptConfig{
+		Keyring:     keys.ring,
+		Save:        func(context.Context, string, string) error { return saveErr },
+		MaxFailures: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := r.Run(context.Background(), SliceIterator(oldRecords(t, keys.old, 12)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 12 || len(report.Failures) != 5 {
+		t.Errorf("failed = %d, failures kept = %d, want 12 and 5", report.Failed, len(report.Failures))
+	}
+	for _, f := range report.Failures {
+		if !errors.Is(f.Err, saveErr) {
+			t.Errorf("failure %s: %v, want save error", f.ID, f.Err)
+		}
+	}
+}
+
+func TestKeyringDetectAmbiguous(t *testing.T) {
+	keys := newTestKeys(t)
+	dup, _ := NewAESCBC([]byte("0123456789abcdef"))
+	ring := NewKeyring(NamedCipher{"new", keys.target}, NamedCipher{"old", keys.old}, NamedCipher{"copy", dup})
+	ct, _ := keys.old.Encrypt([]byte("hello"))
+	if _, _, err := ring.Detect(ct); !errors.Is(err, ErrAmbiguousKey) {
+		t.Errorf("error = %v, want ErrAmbiguousKey", err)
+	}
+}
+
+func TestNewReEncryptorValidation(t *testing.T) {
+	keys := newTestKeys(t)
+	if _, err := NewReEncryptor(ReEncryptConfig{Save: (&memorySink{}).Save}); err == nil {
+		t.Error("NewReEncryptor accepted a nil keyring")
+	}
+	if _, err := NewReEncryptor(ReEncryptConfig{Keyring: keys.ring}); err == nil {
+		t.Error("NewReEncryptor accepted a nil save func")
+	}
+}
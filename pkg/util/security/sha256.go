@@ -1,7 +1,10 @@
 package security
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 )
 
 // SHA256Digest 获取摘要
@@ -10,3 +13,27 @@ func SHA256Digest(data []byte) []byte {
 	s.Write(data)
 	return s.Sum(nil)
 }
+
+// HMACSHA256 HMAC-SHA256 签名
+func HMACSHA256(data, key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// HMACSHA256Base64 HMAC-SHA256 签名，返回base64编码后的签名
+func HMACSHA256Base64(data, key []byte) string {
+	return base64.StdEncoding.EncodeToString(HMACSHA256(data, key))
+}
+
+// VerifyHMACSHA256Base64 HMAC-SHA256 验签，使用常量时间比较
+func VerifyHMACSHA256Base64(data, key []byte, b64Sign string) error {
+	sign, err := base64.StdEncoding.DecodeString(b64Sign)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(sign, HMACSHA256(data, key)) {
+		return errors.New("hmac signature mismatch")
+	}
+	return nil
+}
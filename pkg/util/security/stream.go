@@ -0,0 +1,320 @@
+package security
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// 流式加密格式：
+//
+//	头部 48字节：magic "PKGS"(4) + 版本(1) + 分段大小(4, 大端) + 盐(32) + nonce前缀(7)
+//	分段：每段为 分段大小 的明文加密后的密文 + tag(16)，最后一段可以更短
+//
+// 每个流使用 HKDF-SHA256(密钥, 盐) 派生独立的流密钥，nonce前缀只需在同一流密钥下唯一，
+// 不会因为同一密钥加密大量流而出现nonce碰撞。每段nonce为 nonce前缀(7) + 段序号(4, 大端) + 是否最后一段(1)，
+// 头部作为每段的关联数据，因此段被重排、截断、拼接都会导致认证失败。
+const (
+	// DefaultStreamChunkSize 默认分段大小
+	DefaultStreamChunkSize = 64 * 1024
+	// MaxStreamChunkSize 最大分段大小，解密时超过此值的头部视为非法
+	MaxStreamChunkSize = 16 * 1024 * 1024
+
+	streamVersion1    byte = 1
+	streamSaltSize         = 32
+	streamNoncePrefix      = 7
+	streamHeaderSize       = 9 + streamSaltSize + streamNoncePrefix
+	streamMaxSegments      = 1<<32 - 1
+	streamMagic            = "PKGS"
+	streamKDFInfo          = "pkgutil stream v1"
+)
+
+// 流式加密错误，可使用 errors.Is 判断
+var (
+	ErrInvalidStreamHeader = errors.New("security: invalid stream header")
+	ErrStreamTooLong       = errors.New("security: stream exceeds the maximum number of segments")
+	ErrStreamClosed        = errors.New("security: stream writer is closed")
+)
+
+// NewEncryptWriter 返回分段AES-GCM加密的Writer，使用 DefaultStreamChunkSize 分段，
+// 必须调用 Close 写入最后一段，Close 不会关闭 w
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	return NewEncryptWriterSize(w, key, DefaultStreamChunkSize)
+}
+
+// NewEncryptWriterSize 指定分段大小创建加密Writer
+func NewEncryptWriterSize(w io.Writer, key []byte, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize <= 0 || chunkSize > MaxStreamChunkSize {
+		return nil, ErrInvalidStreamHeader
+	}
+	header := make([]byte, streamHeaderSize)
+	copy(header, streamMagic)
+	header[4] = streamVersion1
+	binary.BigEndian.PutUint32(header[5:9], uint32(chunkSize))
+	if _, err := io.ReadFull(rand.Reader, header[9:]); err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:         w,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize),
+		out:       make([]byte, 0, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+type encryptWriter struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	buf       []byte
+	out       []byte
+	segment   uint64
+	closed    bool
+	err       error
+}
+
+// Write 缓存满一段且还有后续数据时才加密写出，保证 Close 时能把最后一段标记为结束
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, ErrStreamClosed
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	n := 0
+	for len(p) > 0 {
+		if len(e.buf) == e.chunkSize {
+			if e.err = e.flush(false); e.err != nil {
+				return n, e.err
+			}
+		}
+		m := copy(e.buf[len(e.buf):e.chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close 加密写出最后一段
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.flush(true)
+	return e.err
+}
+
+func (e *encryptWriter) flush(final bool) error {
+	if e.segment > streamMaxSegments {
+		return ErrStreamTooLong
+	}
+	e.out = e.aead.Seal(e.out[:0], streamNonce(e.header, e.segment, final), e.buf, e.header)
+	e.segment++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+// NewDecryptReader 返回解密 NewEncryptWriter 输出的Reader，每段认证通过后才返回明文，
+// 流被截断、篡改或重排时返回 ErrAuthFailed
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	if _, err := newAESBlock(key); err != nil {
+		return nil, err
+	}
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header[:5]); err != nil || string(header[:4]) != streamMagic {
+		return nil, ErrInvalidStreamHeader
+	}
+	if header[4] != streamVersion1 {
+		return nil, ErrUnsupportedVersion
+	}
+	if _, err := io.ReadFull(r, header[5:]); err != nil {
+		return nil, ErrInvalidStreamHeader
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:9]))
+	if chunkSize <= 0 || chunkSize > MaxStreamChunkSize {
+		return nil, ErrInvalidStreamHeader
+	}
+	aead, err := newStreamAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:      bufio.NewReader(r),
+		aead:   aead,
+		header: header,
+		in:     make([]byte, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	in      []byte
+	plain   []byte
+	segment uint64
+	done    bool
+	err     error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next 读取并解密一段，读满一段后探测是否还有数据来判断是否为最后一段
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.in)
+	final := false
+	switch err {
+	case nil:
+		if _, perr := d.r.Peek(1); perr == io.EOF {
+			final = true
+		} else if perr != nil {
+			return perr
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		final = true
+	default:
+		return err
+	}
+	if n < d.aead.Overhead() || d.segment > streamMaxSegments {
+		return ErrAuthFailed
+	}
+	plain, err := d.aead.Open(d.in[:0], streamNonce(d.header, d.segment, final), d.in[:n], d.header)
+	if err != nil {
+		return ErrAuthFailed
+	}
+	d.segment++
+	d.plain = plain
+	d.done = final
+	return nil
+}
+
+// newStreamAEAD 使用头部中的盐从 key 派生与 key 等长的流密钥
+func newStreamAEAD(key, header []byte) (cipher.AEAD, error) {
+	if _, err := newAESBlock(key); err != nil {
+		return nil, err
+	}
+	salt := header[9 : 9+streamSaltSize]
+	streamKey := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte(streamKDFInfo)), streamKey); err != nil {
+		return nil, err
+	}
+	block, err := newAESBlock(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func streamNonce(header []byte, segment uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[streamHeaderSize-streamNoncePrefix:])
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefix:], uint32(segment))
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// EncryptFile 流式加密文件，先写入同目录临时文件再重命名，失败时不会留下不完整的 dst
+func EncryptFile(src, dst string, key []byte) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error {
+		ew, err := NewEncryptWriter(w, key)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ew, r); err != nil {
+			return err
+		}
+		return ew.Close()
+	})
+}
+
+// DecryptFile 流式解密文件，全部认证通过后才重命名为 dst
+func DecryptFile(src, dst string, key []byte) error {
+	return transformFile(src, dst, func(w io.Writer, r io.Reader) error {
+		dr, err := NewDecryptReader(r, key)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, dr)
+		return err
+	})
+}
+
+// transformFile 读取 src 经 fn 处理后原子地写入 dst，保留 src 的权限
+func transformFile(src, dst string, fn func(w io.Writer, r io.Reader) error) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	bw := bufio.NewWriter(tmp)
+	if err = fn(bw, bufio.NewReader(in)); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
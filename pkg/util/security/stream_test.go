@@ -0,0 +1,270 @@
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testChunkSize = 64
+
+var testStreamKey = []byte("0123456789abcdef0123456789abcdef")
+
+func encryptStream(t *testing.T, key, plaintext []byte, chunkSize int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptWriterSize(&buf, key, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分多次写入，覆盖跨段缓存的逻辑
+	for p := plaintext; len(p) > 0; {
+		n := 7
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(key, ciphertext []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(ciphertext), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// segments 按分段切分密文，不含头部
+func segments(ct []byte, chunkSize int) [][]byte {
+	body := ct[streamHeaderSize:]
+	size := chunkSize + 16
+	var segs [][]byte
+	for len(body) > size {
+		segs = append(segs, body[:size])
+		body = body[size:]
+	}
+	return append(segs, body)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1, 2 * testChunkSize, 5*testChunkSize + 3}
+	for _, keySize := range []int{16, 24, 32} {
+		key := testStreamKey[:keySize]
+		for _, n := range sizes {
+			pt := randomBytes(t, n)
+			ct := encryptStream(t, key, pt, testChunkSize)
+			wantSegs := n/testChunkSize + 1
+			if n > 0 && n%testChunkSize == 0 {
+				wantSegs = n / testChunkSize
+			}
+			if got := len(segments(ct, testChunkSize)); got != wantSegs {
+				t.Errorf("key %d, size %d: %d segments, want %d", keySize, n, got, wantSegs)
+			}
+			got, err := decryptStream(key, ct)
+			if err != nil {
+				t.Fatalf("key %d, size %d: %v", keySize, n, err)
+			}
+			if !bytes.Equal(got, pt) {
+				t.Fatalf("key %d, size %d: plaintext mismatch", keySize, n)
+			}
+		}
+	}
+}
+
+func TestStreamDerivesPerStreamKey(t *testing.T) {
+	pt := bytes.Repeat([]byte("a"), testChunkSize)
+	a := encryptStream(t, testStreamKey, pt, testChunkSize)
+	b := encryptStream(t, testStreamKey, pt, testChunkSize)
+	if bytes.Equal(a[9:9+streamSaltSize], b[9:9+streamSaltSize]) {
+		t.Fatal("two streams share the same salt")
+	}
+	// 即使nonce前缀相同，盐不同时流密钥也不同，密文不会重复
+	copy(b[streamHeaderSize-streamNoncePrefix:streamHeaderSize], a[streamHeaderSize-streamNoncePrefix:streamHeaderSize])
+	if bytes.Equal(a[streamHeaderSize:], b[streamHeaderSize:]) {
+		t.Error("ciphertext does not depend on the salt")
+	}
+	// 替换盐后派生的流密钥不同，认证失败
+	copy(b[9:9+streamSaltSize], a[9:9+streamSaltSize])
+	if _, err := decryptStream(testStreamKey, b); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("decrypt with swapped salt: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestStreamTruncation(t *testing.T) {
+	pt := randomBytes(t, 3*testChunkSize+10)
+	ct := encryptStream(t, testStreamKey, pt, testChunkSize)
+	segSize := testChunkSize + 16
+
+	for _, cut := range []int{
+		streamHeaderSize + segSize,     // 在段边界截断
+		streamHeaderSize + 2*segSize,   // 在段边界截断
+		streamHeaderSize + segSize + 5, // 在段中间截断
+		len(ct) - 1,                    // 缺少最后一个字节
+	} {
+		_, err := decryptStream(testStreamKey, ct[:cut])
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("truncated at %d: %v, want ErrAuthFailed", cut, err)
+		}
+	}
+	if _, err := decryptStream(testStreamKey, ct[:streamHeaderSize-1]); !errors.Is(err, ErrInvalidStreamHeader) {
+		t.Errorf("truncated header: %v, want ErrInvalidStreamHeader", err)
+	}
+	// 只有头部没有任何段
+	if _, err := decryptStream(testStreamKey, ct[:streamHeaderSize]); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("header only: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestStreamExactMultipleTruncation(t *testing.T) {
+	// 明文长度为分段大小的整数倍时，最后一段是满的，去掉它后前一段也是满的，必须依靠结束标记发现截断
+	pt := randomBytes(t, 3*testChunkSize)
+	ct := encryptStream(t, testStreamKey, pt, testChunkSize)
+	segSize := testChunkSize + 16
+	if len(ct) != streamHeaderSize+3*segSize {
+		t.Fatalf("ciphertext length = %d, want %d", len(ct), streamHeaderSize+3*segSize)
+	}
+	if _, err := decryptStream(testStreamKey, ct[:streamHeaderSize+2*segSize]); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("dropped final full segment: %v, want ErrAuthFailed", err)
+	}
+	// 追加一个段同样认证失败
+	extended := append(append([]byte(nil), ct...), ct[streamHeaderSize:streamHeaderSize+segSize]...)
+	if _, err := decryptStream(testStreamKey, extended); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("appended segment: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestStreamReorderAndTamper(t *testing.T) {
+	pt := randomBytes(t, 3*testChunkSize+10)
+	ct := encryptStream(t, testStreamKey, pt, testChunkSize)
+	segs := segments(ct, testChunkSize)
+
+	reordered := append([]byte(nil), ct[:streamHeaderSize]...)
+	for _, i := range []int{1, 0, 2, 3} {
+		reordered = append(reordered, segs[i]...)
+	}
+	if _, err := decryptStream(testStreamKey, reordered); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("reordered segments: %v, want ErrAuthFailed", err)
+	}
+
+	tampered := append([]byte(nil), ct...)
+	tampered[streamHeaderSize+testChunkSize+20] ^= 1
+	if _, err := decryptStream(testStreamKey, tampered); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("tampered segment: %v, want ErrAuthFailed", err)
+	}
+
+	// 修改头部中的分段大小，头部是关联数据
+	header := append([]byte(nil), ct...)
+	header[8]++
+	if _, err := decryptStream(testStreamKey, header); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("tampered header: %v, want ErrAuthFailed", err)
+	}
+
+	if _, err := decryptStream([]byte("fedcba9876543210fedcba9876543210"), ct); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("wrong key: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestStreamHeaderValidation(t *testing.T) {
+	ct := encryptStream(t, testStreamKey, []byte("hello"), testChunkSize)
+
+	if ct[4] != streamVersion1 {
+		t.Fatalf("header version = %d, want %d", ct[4], streamVersion1)
+	}
+	for _, v := range []byte{0, 2, 0xff} {
+		unknown := append([]byte(nil), ct...)
+		unknown[4] = v
+		if _, err := decryptStream(testStreamKey, unknown); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("version %d header: %v, want ErrUnsupportedVersion", v, err)
+		}
+	}
+	bad := append([]byte(nil), ct...)
+	copy(bad, "XXXX")
+	if _, err := decryptStream(testStreamKey, bad); !errors.Is(err, ErrInvalidStreamHeader) {
+		t.Errorf("bad magic: %v, want ErrInvalidStreamHeader", err)
+	}
+	if _, err := NewEncryptWriterSize(io.Discard, testStreamKey, MaxStreamChunkSize+1); !errors.Is(err, ErrInvalidStreamHeader) {
+		t.Errorf("oversized chunk: %v, want ErrInvalidStreamHeader", err)
+	}
+	if _, err := NewEncryptWriter(io.Discard, []byte("short")); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("short key: %v, want ErrInvalidKeySize", err)
+	}
+}
+
+func TestStreamWriteAfterClose(t *testing.T) {
+	w, err := NewEncryptWriter(io.Discard, testStreamKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("write after close: %v, want ErrStreamClosed", err)
+	}
+}
+
+func TestEncryptDecryptFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.txt")
+	enc := filepath.Join(dir, "plain.txt.enc")
+	dec := filepath.Join(dir, "plain.out")
+	pt := randomBytes(t, 3*DefaultStreamChunkSize+17)
+	if err := os.WriteFile(src, pt, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EncryptFile(src, enc, testStreamKey); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(enc); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Fatalf("encrypted file mode = %v, %v, want 0640", fi.Mode().Perm(), err)
+	}
+	if err := DecryptFile(enc, dec, testStreamKey); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Fatal("decrypted file mismatch")
+	}
+
+	// 认证失败时不生成目标文件，也不留下临时文件
+	data, _ := os.ReadFile(enc)
+	if err := os.WriteFile(enc, data[:len(data)-1], 0o640); err != nil {
+		t.Fatal(err)
+	}
+	failed := filepath.Join(dir, "failed.out")
+	if err := DecryptFile(enc, failed, testStreamKey); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("decrypt truncated file: %v, want ErrAuthFailed", err)
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("destination exists after failed decrypt: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("directory has %d entries, want 3", len(entries))
+	}
+}